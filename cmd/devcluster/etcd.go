@@ -0,0 +1,40 @@
+// cmd/devcluster/etcd.go
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// startEtcd runs a single-member etcd in dir, serving clients on
+// localhost:clientPort and its peer port on localhost:peerPort.
+func startEtcd(dir string, clientPort, peerPort int) (*embed.Etcd, error) {
+	cfg := embed.NewConfig()
+	cfg.Name = "devcluster"
+	cfg.Dir = dir
+	// startup failures are returned; its error logs on shutdown are noise
+	cfg.LogLevel = "fatal"
+	client := url.URL{Scheme: "http", Host: fmt.Sprintf("localhost:%d", clientPort)}
+	peer := url.URL{Scheme: "http", Host: fmt.Sprintf("localhost:%d", peerPort)}
+	cfg.ListenClientUrls, cfg.AdvertiseClientUrls = []url.URL{client}, []url.URL{client}
+	cfg.ListenPeerUrls, cfg.AdvertisePeerUrls = []url.URL{peer}, []url.URL{peer}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-e.Server.ReadyNotify():
+		return e, nil
+	case err := <-e.Err():
+		e.Close()
+		return nil, err
+	case <-time.After(30 * time.Second):
+		e.Close()
+		return nil, fmt.Errorf("etcd not ready after 30s")
+	}
+}
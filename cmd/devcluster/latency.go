@@ -0,0 +1,77 @@
+// cmd/devcluster/latency.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// latencies is the artificial round-trip time between regions: a default
+// for any two different regions, overridden for some pairs.
+type latencies struct {
+	def   time.Duration
+	pairs map[[2]string]time.Duration
+}
+
+// parseLatencies parses "d,region/region=d,...": a bare duration is the
+// default, pairs are symmetric. An empty spec adds no latency.
+func parseLatencies(spec string) (latencies, error) {
+	l := latencies{pairs: make(map[[2]string]time.Duration)}
+	if spec == "" {
+		return l, nil
+	}
+	for _, part := range strings.Split(spec, ",") {
+		pair, value, isPair := strings.Cut(strings.TrimSpace(part), "=")
+		if !isPair {
+			value = pair
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return l, fmt.Errorf("bad latency %q", part)
+		}
+		if !isPair {
+			l.def = d
+			continue
+		}
+		a, b, ok := strings.Cut(pair, "/")
+		if !ok || a == "" || b == "" {
+			return l, fmt.Errorf("bad region pair %q (want region/region)", pair)
+		}
+		l.pairs[[2]string{a, b}] = d
+		l.pairs[[2]string{b, a}] = d
+	}
+	return l, nil
+}
+
+// between returns the round-trip time added between regions a and b.
+func (l latencies) between(a, b string) time.Duration {
+	if a == b {
+		return 0
+	}
+	if d, ok := l.pairs[[2]string{a, b}]; ok {
+		return d
+	}
+	return l.def
+}
+
+// interceptor delays every call from region to a storage node by the
+// latency between their regions; regionOf maps node addresses to regions.
+func (l latencies) interceptor(region string, regionOf map[string]string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if d := l.between(region, regionOf[cc.Target()]); d > 0 {
+			t := time.NewTimer(d)
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				return status.FromContextError(ctx.Err()).Err()
+			}
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
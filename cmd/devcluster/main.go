@@ -0,0 +1,218 @@
+// cmd/devcluster/main.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/health"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/kvstore"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/metadata"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/proxy"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/proto"
+)
+
+// devcluster runs a whole cluster in one process for development: the
+// metadata store, storage servers spread over the regions, one proxy per
+// region, and the ring configuration listing the servers.
+func main() {
+	servers := flag.Int("servers", 3, "number of storage servers")
+	regionList := flag.String("regions", "us-east,eu-west", "comma-separated regions; servers are spread over them and each gets a proxy")
+	serverPort := flag.Int("server-port", 50051, "port of the first storage server; the others follow")
+	proxyPort := flag.Int("proxy-port", 8080, "port of the first region's proxy; the others follow")
+	dataDir := flag.String("data", "devcluster-data", "directory for the WAL files and etcd data")
+	backend := flag.String("metadata", "etcd", "metadata backend: etcd (embedded, reachable by kvctl) or memory (in-process only)")
+	etcdPort := flag.Int("etcd-port", 2379, "client port of the embedded etcd")
+	etcdPeerPort := flag.Int("etcd-peer-port", 2380, "peer port of the embedded etcd")
+	vnodes := flag.Int("vnodes", 100, "number of virtual nodes per physical node")
+	R := flag.Int("replicas", 3, "replication factor, at most -servers")
+	writeQuorum := flag.Int("write-quorum", 0, "acknowledgements a write needs (0 means every replica, counting substitutes)")
+	latencySpec := flag.String("latency", "", `round-trip time added between a proxy and a server in different regions: a default and/or pairs, e.g. "40ms,us-east/ap-south=150ms"`)
+	flag.Parse()
+
+	regions := strings.Split(*regionList, ",")
+	if *servers < 1 || *regionList == "" {
+		log.Fatalf("need at least one server and one region")
+	}
+	if *R > *servers {
+		*R = *servers
+	}
+	lat, err := parseLatencies(*latencySpec)
+	if err != nil {
+		log.Fatalf("-latency: %v", err)
+	}
+	if err := os.MkdirAll(*dataDir, 0o755); err != nil {
+		log.Fatalf("create data directory: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var md metadata.Store
+	switch *backend {
+	case "etcd":
+		e, err := startEtcd(filepath.Join(*dataDir, "etcd"), *etcdPort, *etcdPeerPort)
+		if err != nil {
+			log.Fatalf("start etcd: %v", err)
+		}
+		defer e.Close()
+		client, err := metadata.NewClient([]string{fmt.Sprintf("localhost:%d", *etcdPort)})
+		if err != nil {
+			log.Fatalf("failed to connect to etcd: %v", err)
+		}
+		// closed before etcd, which waits for open watches otherwise
+		defer client.Close()
+		md = client
+		log.Printf("etcd listening on localhost:%d", *etcdPort)
+	case "memory":
+		md = metadata.NewMemory()
+	default:
+		log.Fatalf("-metadata must be etcd or memory")
+	}
+
+	// Storage servers, round-robin over the regions.
+	cfg := hashring.Config{VNodes: *vnodes, Regions: make(map[string]string)}
+	for i := 0; i < *servers; i++ {
+		info := metadata.NodeInfo{
+			Addr:     fmt.Sprintf("localhost:%d", *serverPort+i),
+			Region:   regions[i%len(regions)],
+			Capacity: 1,
+		}
+		info.ID = info.Addr
+		wal := filepath.Join(*dataDir, fmt.Sprintf("server-%d.wal", i))
+		srv, err := startServer(ctx, md, info, wal, *vnodes, *R)
+		if err != nil {
+			log.Fatalf("server %s: %v", info.Addr, err)
+		}
+		defer srv.close()
+		cfg.Nodes = append(cfg.Nodes, info.Addr)
+		cfg.Regions[info.Addr] = info.Region
+	}
+
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		log.Fatalf("encode ring config: %v", err)
+	}
+	if _, err := md.SetRingConfig(raw, metadata.AnyRevision); err != nil {
+		log.Fatalf("write /ring/config: %v", err)
+	}
+	log.Printf("ring: %s", strings.Join(cfg.Nodes, ", "))
+
+	// One proxy per region.
+	for i, region := range regions {
+		addr := fmt.Sprintf("localhost:%d", *proxyPort+i)
+		p, err := startProxy(ctx, md, addr, region, *vnodes, *R, *writeQuorum, lat.interceptor(region, cfg.Regions))
+		if err != nil {
+			log.Fatalf("proxy %s: %v", addr, err)
+		}
+		defer p.Stop()
+		log.Printf("proxy for %s listening on %s", region, addr)
+	}
+
+	log.Printf("cluster ready; interrupt to stop")
+	<-ctx.Done()
+	log.Printf("shutting down")
+}
+
+// server is a running storage server.
+type server struct {
+	grpc   *grpc.Server
+	health *grpchealth.Server
+	store  *kvstore.KVStore
+	lease  metadata.Lease
+}
+
+// startServer opens the WAL at wal and serves it on info.Addr, registered
+// and following the ring like cmd/server does with -etcd.
+func startServer(ctx context.Context, md metadata.Store, info metadata.NodeInfo, wal string, vnodes, R int) (*server, error) {
+	store, err := kvstore.NewWALStore(wal)
+	if err != nil {
+		return nil, fmt.Errorf("open WAL store: %w", err)
+	}
+	if err := store.Replay(); err != nil {
+		return nil, fmt.Errorf("replay WAL: %w", err)
+	}
+	go store.RunTombstoneGC(ctx, kvstore.DefaultTombstoneTTL)
+	lis, err := net.Listen("tcp", info.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+
+	ring := hashring.New(vnodes)
+	md.WatchRingConfig(ring.Update)
+	md.WatchReplicas(ring.UpdateReplicas)
+	md.WatchPolicies(ring.UpdatePolicy)
+	md.WatchResidency(ring.UpdateResidency)
+	svc := kvstore.NewService(store)
+	svc.Ring = ring
+	svc.NodeID = info.Addr
+	svc.R = R
+
+	s := &server{grpc: grpc.NewServer(), health: grpchealth.NewServer(), store: store}
+	proto.RegisterKVServer(s.grpc, svc)
+	healthpb.RegisterHealthServer(s.grpc, s.health)
+	go s.grpc.Serve(lis)
+
+	if s.lease, err = md.Register(ctx, info); err != nil {
+		s.close()
+		return nil, fmt.Errorf("register: %w", err)
+	}
+	log.Printf("server %s (region %s) listening, WAL %s", info.Addr, info.Region, wal)
+	return s, nil
+}
+
+func (s *server) close() {
+	if s.lease != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		s.lease.Close(ctx)
+		cancel()
+	}
+	s.health.Shutdown()
+	s.grpc.GracefulStop()
+	s.store.Close()
+}
+
+// startProxy serves a proxy for region on addr, delaying its calls to the
+// storage servers with latency.
+func startProxy(ctx context.Context, md metadata.Store, addr, region string, vnodes, R, writeQuorum int, latency grpc.UnaryClientInterceptor) (*grpc.Server, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+	ring := hashring.New(vnodes)
+	md.WatchRingConfig(ring.Update)
+	md.WatchReplicas(ring.UpdateReplicas)
+	md.WatchPolicies(ring.UpdatePolicy)
+	md.WatchResidency(ring.UpdateResidency)
+
+	tracker := health.NewTracker()
+	ring.Healthy = tracker.Healthy
+	go tracker.Run(ctx, ring.AllNodes)
+
+	svc := proxy.NewProxyServer(ring, md, R)
+	svc.Region = region
+	svc.Health = tracker
+	svc.WriteQuorum = writeQuorum
+	svc.DialOptions = []grpc.DialOption{grpc.WithUnaryInterceptor(latency)}
+	go svc.RunHandoff(ctx, 10*time.Second)
+
+	grpcServer := grpc.NewServer()
+	proto.RegisterKVServer(grpcServer, svc)
+	go grpcServer.Serve(lis)
+	return grpcServer, nil
+}
@@ -0,0 +1,116 @@
+// cmd/kvbench/histogram.go
+package main
+
+import (
+	"math"
+	"math/bits"
+)
+
+// subBucketBits sets the histogram's precision: values are kept with
+// subBucketBits-1 significant bits, i.e. within 0.1% (3 decimal digits).
+const subBucketBits = 11
+
+// histogram is an HDR-style histogram of non-negative integer values
+// (latencies in microseconds): exact below 2^subBucketBits, then log-linear
+// buckets whose width doubles with every power of two, so any value is
+// recorded within a constant relative error in constant time and memory.
+type histogram struct {
+	counts []uint64
+	total  uint64
+	sum    float64
+	min    int64
+	max    int64
+}
+
+// bucket returns the index counting v.
+func bucket(v int64) int {
+	if v < 1<<subBucketBits {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - subBucketBits
+	half := 1 << (subBucketBits - 1)
+	return 1<<subBucketBits + (shift-1)*half + int(v>>shift) - half
+}
+
+// highest returns the largest value counted at index i.
+func highest(i int) int64 {
+	if i < 1<<subBucketBits {
+		return int64(i)
+	}
+	half := 1 << (subBucketBits - 1)
+	k := i - 1<<subBucketBits
+	shift := k/half + 1
+	sub := int64(k%half + half)
+	return (sub+1)<<shift - 1
+}
+
+// record counts one value; negative values count as 0.
+func (h *histogram) record(v int64) {
+	if v < 0 {
+		v = 0
+	}
+	i := bucket(v)
+	if i >= len(h.counts) {
+		grown := make([]uint64, i+1, 2*(i+1))
+		copy(grown, h.counts)
+		h.counts = grown
+	}
+	h.counts[i]++
+	if h.total == 0 || v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+	h.total++
+	h.sum += float64(v)
+}
+
+// merge adds the values of o.
+func (h *histogram) merge(o *histogram) {
+	if o.total == 0 {
+		return
+	}
+	if len(o.counts) > len(h.counts) {
+		grown := make([]uint64, len(o.counts))
+		copy(grown, h.counts)
+		h.counts = grown
+	}
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	if h.total == 0 || o.min < h.min {
+		h.min = o.min
+	}
+	if o.max > h.max {
+		h.max = o.max
+	}
+	h.total += o.total
+	h.sum += o.sum
+}
+
+// quantile returns the value below which a fraction q of the values lie,
+// rounded up to the precision of its bucket.
+func (h *histogram) quantile(q float64) int64 {
+	if h.total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(h.total)))
+	if rank == 0 {
+		rank = 1
+	}
+	var seen uint64
+	for i, c := range h.counts {
+		if seen += c; seen >= rank {
+			return min(highest(i), h.max)
+		}
+	}
+	return h.max
+}
+
+func (h *histogram) mean() float64 {
+	if h.total == 0 {
+		return 0
+	}
+	return h.sum / float64(h.total)
+}
@@ -0,0 +1,363 @@
+// cmd/kvbench/main.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/grpc"
+	grpcmd "google.golang.org/grpc/metadata"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/proxy"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/proto"
+)
+
+// Operations, as reported.
+const (
+	opRead  = "read"
+	opWrite = "write"
+)
+
+// config is the benchmark's settings, echoed in the JSON report.
+type config struct {
+	Proxies     []string      `json:"proxies"`
+	Duration    time.Duration `json:"duration_ns"`
+	Requests    int64         `json:"requests,omitempty"`
+	Concurrency int           `json:"concurrency"`
+	Keys        int           `json:"keys"`
+	KeyPrefix   string        `json:"key_prefix"`
+	Dist        string        `json:"distribution"`
+	ZipfS       float64       `json:"zipf_s,omitempty"`
+	HotKeys     float64       `json:"hot_keys,omitempty"`
+	HotOps      float64       `json:"hot_ops,omitempty"`
+	ReadRatio   float64       `json:"read_ratio"`
+	ValueSize   string        `json:"value_size"`
+	Regions     string        `json:"regions,omitempty"`
+	Timeout     time.Duration `json:"timeout_ns"`
+	Seed        int64         `json:"seed"`
+}
+
+// stat accumulates one worker's results for a region and operation.
+type stat struct {
+	ops, errors, notFound int64
+	lastErr               string
+	latency               histogram // microseconds
+}
+
+type statKey struct{ region, op string }
+
+// kvbench drives the proxies with a synthetic geo-distributed workload and
+// reports throughput and latency percentiles per client region and
+// operation.
+func main() {
+	var cfg config
+	proxies := flag.String("proxy", "localhost:8080", "comma-separated proxy addresses; workers spread over them")
+	flag.DurationVar(&cfg.Duration, "duration", 30*time.Second, "how long to run")
+	flag.Int64Var(&cfg.Requests, "requests", 0, "stop after this many requests (0: run for -duration)")
+	flag.IntVar(&cfg.Concurrency, "concurrency", 16, "number of concurrent clients")
+	flag.IntVar(&cfg.Keys, "keys", 100000, "size of the key space")
+	flag.StringVar(&cfg.KeyPrefix, "key-prefix", "bench-", "prefix of the generated keys")
+	flag.StringVar(&cfg.Dist, "dist", distZipfian, "key distribution: uniform, zipfian or hotspot")
+	flag.Float64Var(&cfg.ZipfS, "zipf-s", 1.1, "zipfian skew, greater than 1")
+	flag.Float64Var(&cfg.HotKeys, "hot-keys", 0.01, "hotspot: fraction of the key space that is hot")
+	flag.Float64Var(&cfg.HotOps, "hot-ops", 0.9, "hotspot: fraction of the requests that go to hot keys")
+	flag.Float64Var(&cfg.ReadRatio, "read-ratio", 0.9, "fraction of requests that are reads")
+	flag.StringVar(&cfg.ValueSize, "value-size", "256", "value size in bytes, or a uniform range min-max")
+	flag.StringVar(&cfg.Regions, "regions", "", `simulated client regions with weights, e.g. "us-east=3,eu-west=1" (default: the proxy's region)`)
+	flag.DurationVar(&cfg.Timeout, "timeout", 2*time.Second, "per-request timeout")
+	flag.Int64Var(&cfg.Seed, "seed", 1, "random seed")
+	preload := flag.Bool("preload", false, "write every key once before measuring, so reads find them")
+	out := flag.String("o", "", "write the JSON report to this file")
+	flag.Parse()
+	cfg.Proxies = strings.Split(*proxies, ",")
+
+	regions, err := parseRegions(cfg.Regions)
+	if err != nil {
+		log.Fatalf("-regions: %v", err)
+	}
+	sizes, err := parseValueSize(cfg.ValueSize)
+	if err != nil {
+		log.Fatalf("-value-size: %v", err)
+	}
+	if cfg.Keys < 1 || cfg.Concurrency < 1 {
+		log.Fatalf("-keys and -concurrency must be positive")
+	}
+	// fail on a bad distribution before connecting
+	if _, err := newChooser(rand.New(rand.NewSource(cfg.Seed)), cfg.Dist, cfg.Keys, cfg.ZipfS, cfg.HotKeys, cfg.HotOps); err != nil {
+		log.Fatalf("-dist: %v", err)
+	}
+
+	clients := make([]proto.KVClient, len(cfg.Proxies))
+	for i, addr := range cfg.Proxies {
+		conn, err := grpc.Dial(addr, grpc.WithInsecure())
+		if err != nil {
+			log.Fatalf("dial %s: %v", addr, err)
+		}
+		defer conn.Close()
+		clients[i] = proto.NewKVClient(conn)
+	}
+
+	if *preload {
+		start := time.Now()
+		if err := load(clients, cfg, sizes); err != nil {
+			log.Fatalf("preload: %v", err)
+		}
+		log.Printf("preloaded %d keys in %s", cfg.Keys, time.Since(start).Round(time.Millisecond))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Duration)
+	defer cancel()
+	var issued atomic.Int64
+	results := make([]map[statKey]*stat, cfg.Concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < cfg.Concurrency; w++ {
+		rng := rand.New(rand.NewSource(cfg.Seed + int64(w) + 1))
+		keys, _ := newChooser(rng, cfg.Dist, cfg.Keys, cfg.ZipfS, cfg.HotKeys, cfg.HotOps)
+		results[w] = make(map[statKey]*stat)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client := clients[w%len(clients)]
+			stats := results[w]
+			value := make([]byte, sizes.max)
+			for ctx.Err() == nil {
+				if cfg.Requests > 0 && issued.Add(1) > cfg.Requests {
+					return
+				}
+				region := regions.pick(rng)
+				key := cfg.KeyPrefix + strconv.Itoa(keys.next())
+				op := opWrite
+				if rng.Float64() < cfg.ReadRatio {
+					op = opRead
+				}
+				rctx, rcancel := context.WithTimeout(ctx, cfg.Timeout)
+				if region != "" {
+					rctx = grpcmd.AppendToOutgoingContext(rctx, proxy.RegionHeader, region)
+				}
+				begin := time.Now()
+				var err error
+				found := true
+				if op == opRead {
+					var reply *proto.GetReply
+					reply, err = client.Get(rctx, &proto.GetRequest{Key: key})
+					found = err != nil || reply.Found
+				} else {
+					rng.Read(value)
+					_, err = client.Put(rctx, &proto.PutRequest{Key: key, Value: value[:sizes.pick(rng)]})
+				}
+				elapsed := time.Since(begin)
+				rcancel()
+				if ctx.Err() != nil {
+					// cut off by the end of the run, not a real result
+					return
+				}
+
+				s := stats[statKey{region, op}]
+				if s == nil {
+					s = &stat{}
+					stats[statKey{region, op}] = s
+				}
+				s.ops++
+				switch {
+				case err != nil:
+					s.errors++
+					s.lastErr = err.Error()
+				case !found:
+					s.notFound++
+				}
+				s.latency.record(elapsed.Microseconds())
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	rep := summarize(cfg, results, elapsed)
+	printReport(rep)
+	if *out != "" {
+		buf, err := json.MarshalIndent(rep, "", "  ")
+		if err != nil {
+			log.Fatalf("encode report: %v", err)
+		}
+		if err := os.WriteFile(*out, append(buf, '\n'), 0o644); err != nil {
+			log.Fatalf("write %s: %v", *out, err)
+		}
+	}
+}
+
+// load writes every key once, spreading the keys over the clients.
+func load(clients []proto.KVClient, cfg config, sizes valueSize) error {
+	next := make(chan int)
+	errs := make(chan error, cfg.Concurrency)
+	var wg sync.WaitGroup
+	for w := 0; w < cfg.Concurrency; w++ {
+		rng := rand.New(rand.NewSource(cfg.Seed - int64(w) - 1))
+		client := clients[w%len(clients)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value := make([]byte, sizes.max)
+			for i := range next {
+				rng.Read(value)
+				ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+				_, err := client.Put(ctx, &proto.PutRequest{Key: cfg.KeyPrefix + strconv.Itoa(i), Value: value[:sizes.pick(rng)]})
+				cancel()
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	var err error
+	for i := 0; i < cfg.Keys && err == nil; i++ {
+		select {
+		case next <- i:
+		case err = <-errs:
+		}
+	}
+	close(next)
+	wg.Wait()
+	if err == nil {
+		select {
+		case err = <-errs:
+		default:
+		}
+	}
+	return err
+}
+
+// report is the benchmark result, as exported to JSON.
+type report struct {
+	Config  config   `json:"config"`
+	Elapsed float64  `json:"elapsed_s"`
+	Total   result   `json:"total"`
+	Results []result `json:"results"` // by region, then operation
+}
+
+// result summarizes the requests of a region and operation; the total has
+// neither.
+type result struct {
+	Region     string    `json:"region,omitempty"`
+	Op         string    `json:"op,omitempty"`
+	Ops        int64     `json:"ops"`
+	Errors     int64     `json:"errors"`
+	NotFound   int64     `json:"not_found,omitempty"`
+	LastError  string    `json:"last_error,omitempty"`
+	Throughput float64   `json:"throughput"` // requests per second
+	Latency    latencies `json:"latency_us"`
+}
+
+type latencies struct {
+	Mean float64 `json:"mean"`
+	Min  int64   `json:"min"`
+	P50  int64   `json:"p50"`
+	P90  int64   `json:"p90"`
+	P99  int64   `json:"p99"`
+	P999 int64   `json:"p999"`
+	Max  int64   `json:"max"`
+}
+
+// summarize merges the workers' statistics.
+func summarize(cfg config, results []map[statKey]*stat, elapsed time.Duration) report {
+	merged := make(map[statKey]*stat)
+	total := &stat{}
+	for _, stats := range results {
+		for k, s := range stats {
+			m := merged[k]
+			if m == nil {
+				m = &stat{}
+				merged[k] = m
+			}
+			for _, into := range []*stat{m, total} {
+				into.ops += s.ops
+				into.errors += s.errors
+				into.notFound += s.notFound
+				into.latency.merge(&s.latency)
+				if s.lastErr != "" {
+					into.lastErr = s.lastErr
+				}
+			}
+		}
+	}
+
+	keys := make([]statKey, 0, len(merged))
+	for k := range merged {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].region != keys[j].region {
+			return keys[i].region < keys[j].region
+		}
+		return keys[i].op < keys[j].op
+	})
+	rep := report{Config: cfg, Elapsed: elapsed.Seconds(), Total: summary(total, elapsed)}
+	for _, k := range keys {
+		r := summary(merged[k], elapsed)
+		r.Region, r.Op = k.region, k.op
+		rep.Results = append(rep.Results, r)
+	}
+	return rep
+}
+
+func summary(s *stat, elapsed time.Duration) result {
+	h := &s.latency
+	return result{
+		Ops:        s.ops,
+		Errors:     s.errors,
+		NotFound:   s.notFound,
+		LastError:  s.lastErr,
+		Throughput: float64(s.ops) / elapsed.Seconds(),
+		Latency: latencies{
+			Mean: h.mean(),
+			Min:  h.min,
+			P50:  h.quantile(0.50),
+			P90:  h.quantile(0.90),
+			P99:  h.quantile(0.99),
+			P999: h.quantile(0.999),
+			Max:  h.max,
+		},
+	}
+}
+
+func printReport(rep report) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "region\top\tops\terrors\tops/s\tmean\tp50\tp90\tp99\tp99.9\tmax\t")
+	row := func(region, op string, r result) {
+		l := r.Latency
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%.0f\t%s\t%s\t%s\t%s\t%s\t%s\t\n", region, op, r.Ops, r.Errors, r.Throughput,
+			micros(int64(l.Mean)), micros(l.P50), micros(l.P90), micros(l.P99), micros(l.P999), micros(l.Max))
+	}
+	for _, r := range rep.Results {
+		region := r.Region
+		if region == "" {
+			region = "-"
+		}
+		row(region, r.Op, r)
+	}
+	row("all", "", rep.Total)
+	tw.Flush()
+	for _, r := range rep.Results {
+		if r.LastError != "" {
+			log.Printf("%s %s: %d errors, last: %s", r.Region, r.Op, r.Errors, r.LastError)
+		}
+	}
+}
+
+// micros formats a latency in microseconds.
+func micros(us int64) string {
+	return time.Duration(us * int64(time.Microsecond)).String()
+}
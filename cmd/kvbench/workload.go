@@ -0,0 +1,141 @@
+// cmd/kvbench/workload.go
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Key distributions.
+const (
+	distUniform = "uniform"
+	distZipfian = "zipfian"
+	distHotspot = "hotspot"
+)
+
+// keyChooser picks the index of the next key to access.
+type keyChooser interface {
+	next() int
+}
+
+type uniform struct {
+	rng  *rand.Rand
+	keys int
+}
+
+func (u uniform) next() int { return u.rng.Intn(u.keys) }
+
+// zipfian favors low key indexes: key i is accessed in proportion to
+// 1/(i+1)^s.
+type zipfian struct{ z *rand.Zipf }
+
+func (z zipfian) next() int { return int(z.z.Uint64()) }
+
+// hotspot sends a fraction ops of the accesses to the first fraction keys
+// of the key space, uniformly, and the rest to the other keys.
+type hotspot struct {
+	rng       *rand.Rand
+	keys, hot int
+	ops       float64
+}
+
+func (h hotspot) next() int {
+	if h.hot > 0 && (h.hot == h.keys || h.rng.Float64() < h.ops) {
+		return h.rng.Intn(h.hot)
+	}
+	return h.hot + h.rng.Intn(h.keys-h.hot)
+}
+
+// newChooser builds the key distribution named dist over keys keys.
+func newChooser(rng *rand.Rand, dist string, keys int, zipfS, hotKeys, hotOps float64) (keyChooser, error) {
+	switch dist {
+	case distUniform:
+		return uniform{rng: rng, keys: keys}, nil
+	case distZipfian:
+		if zipfS <= 1 {
+			return nil, fmt.Errorf("-zipf-s must be greater than 1, got %v", zipfS)
+		}
+		return zipfian{z: rand.NewZipf(rng, zipfS, 1, uint64(keys-1))}, nil
+	case distHotspot:
+		if hotKeys <= 0 || hotKeys > 1 || hotOps < 0 || hotOps > 1 {
+			return nil, fmt.Errorf("-hot-keys must be in (0, 1] and -hot-ops in [0, 1]")
+		}
+		hot := int(hotKeys * float64(keys))
+		if hot < 1 {
+			hot = 1
+		}
+		return hotspot{rng: rng, keys: keys, hot: hot, ops: hotOps}, nil
+	default:
+		return nil, fmt.Errorf("unknown key distribution %q (want %s, %s or %s)", dist, distUniform, distZipfian, distHotspot)
+	}
+}
+
+// regionMix picks the simulated client region of each request.
+type regionMix struct {
+	names []string
+	cum   []float64 // cumulative weights, normalized to end at 1
+}
+
+// parseRegions parses "name=weight,..." ("name" alone weighs 1). An empty
+// spec yields a single unnamed region: the proxy's own.
+func parseRegions(spec string) (regionMix, error) {
+	var m regionMix
+	if spec == "" {
+		return regionMix{names: []string{""}, cum: []float64{1}}, nil
+	}
+	total := 0.0
+	for _, part := range strings.Split(spec, ",") {
+		name, weight, hasWeight := strings.Cut(strings.TrimSpace(part), "=")
+		w := 1.0
+		if hasWeight {
+			var err error
+			if w, err = strconv.ParseFloat(weight, 64); err != nil || w < 0 {
+				return m, fmt.Errorf("region %q: bad weight %q", name, weight)
+			}
+		}
+		total += w
+		m.names = append(m.names, name)
+		m.cum = append(m.cum, total)
+	}
+	if total <= 0 {
+		return m, fmt.Errorf("region weights sum to zero")
+	}
+	for i := range m.cum {
+		m.cum[i] /= total
+	}
+	return m, nil
+}
+
+func (m regionMix) pick(rng *rand.Rand) string {
+	i := sort.SearchFloat64s(m.cum, rng.Float64())
+	if i == len(m.names) {
+		i--
+	}
+	return m.names[i]
+}
+
+// valueSize is a fixed size or a uniform range "min-max".
+type valueSize struct{ min, max int }
+
+func parseValueSize(spec string) (valueSize, error) {
+	lo, hi, isRange := strings.Cut(spec, "-")
+	a, err := strconv.Atoi(lo)
+	if err != nil || a < 0 {
+		return valueSize{}, fmt.Errorf("bad value size %q", spec)
+	}
+	if !isRange {
+		return valueSize{a, a}, nil
+	}
+	b, err := strconv.Atoi(hi)
+	if err != nil || b < a {
+		return valueSize{}, fmt.Errorf("bad value size %q", spec)
+	}
+	return valueSize{a, b}, nil
+}
+
+func (v valueSize) pick(rng *rand.Rand) int {
+	return v.min + rng.Intn(v.max-v.min+1)
+}
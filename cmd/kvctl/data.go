@@ -0,0 +1,186 @@
+// cmd/kvctl/data.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/proto"
+)
+
+// entry is a key as the data commands print it.
+type entry struct {
+	Key     string   `json:"key"`
+	Value   string   `json:"value"`
+	Version uint64   `json:"version"`
+	Nodes   []string `json:"nodes,omitempty"` // nodes holding this version (scan)
+}
+
+func (e entry) row() []string {
+	return []string{e.Key, e.Value, strconv.FormatUint(e.Version, 10)}
+}
+
+// kv dials the proxy.
+func (o *options) kv() (proto.KVClient, func()) {
+	conn, err := grpc.Dial(o.proxy, grpc.WithInsecure())
+	if err != nil {
+		log.Fatalf("dial %s: %v", o.proxy, err)
+	}
+	return proto.NewKVClient(conn), func() { conn.Close() }
+}
+
+// getCmd reads a key through the proxy.
+//
+//	kvctl get <key>
+func getCmd(args []string) {
+	fs, o := newFlags("get")
+	key := parse(fs, o, args, 1, "<key>")[0]
+	client, closeFn := o.kv()
+	defer closeFn()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	reply, err := client.Get(ctx, &proto.GetRequest{Key: key})
+	if err != nil {
+		log.Fatalf("get %s: %v", key, err)
+	}
+	if !reply.Found {
+		log.Fatalf("get %s: not found", key)
+	}
+	e := entry{Key: key, Value: string(reply.Value), Version: reply.Version}
+	o.print(e, []string{"KEY", "VALUE", "VERSION"}, [][]string{e.row()})
+}
+
+// putCmd writes a key through the proxy.
+//
+//	kvctl put <key> <value>
+func putCmd(args []string) {
+	fs, o := newFlags("put")
+	pos := parse(fs, o, args, 2, "<key> <value>")
+	client, closeFn := o.kv()
+	defer closeFn()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	reply, err := client.Put(ctx, &proto.PutRequest{Key: pos[0], Value: []byte(pos[1])})
+	if err != nil {
+		log.Fatalf("put %s: %v", pos[0], err)
+	}
+	e := entry{Key: pos[0], Value: pos[1], Version: reply.Version}
+	o.print(e, []string{"KEY", "VALUE", "VERSION"}, [][]string{e.row()})
+}
+
+// deleteCmd deletes a key through the proxy.
+//
+//	kvctl delete <key>
+func deleteCmd(args []string) {
+	fs, o := newFlags("delete")
+	key := parse(fs, o, args, 1, "<key>")[0]
+	client, closeFn := o.kv()
+	defer closeFn()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	reply, err := client.Delete(ctx, &proto.DeleteRequest{Key: key})
+	if err != nil {
+		log.Fatalf("delete %s: %v", key, err)
+	}
+	result := struct {
+		Key     string `json:"key"`
+		Deleted bool   `json:"deleted"`
+	}{key, reply.Deleted}
+	o.print(result, []string{"KEY", "DELETED"}, [][]string{{key, strconv.FormatBool(reply.Deleted)}})
+}
+
+// scanCmd lists keys by scanning every storage node in the ring, keeping
+// the newest version of each key. The proxy routes single keys only, so
+// scan goes to the nodes directly.
+//
+//	kvctl scan [-prefix p] [-limit n]
+func scanCmd(args []string) {
+	fs, o := newFlags("scan")
+	prefix := fs.String("prefix", "", "only list keys with this prefix")
+	limit := fs.Int("limit", 100, "list at most this many keys (0 lists all)")
+	parse(fs, o, args, 0, "[-prefix p] [-limit n]")
+
+	md := o.metadata()
+	raw, _, err := md.RingConfig()
+	if err != nil {
+		log.Fatalf("read ring config: %v", err)
+	}
+	var cfg hashring.Config
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			log.Fatalf("decode ring config: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	newest := make(map[string]*entry)
+	for _, node := range cfg.Nodes {
+		err := scanNode(ctx, node, func(kv *proto.KeyValue) {
+			if !strings.HasPrefix(kv.Key, *prefix) {
+				return
+			}
+			e, ok := newest[kv.Key]
+			switch {
+			case !ok || kv.Version > e.Version:
+				newest[kv.Key] = &entry{Key: kv.Key, Value: string(kv.Value), Version: kv.Version, Nodes: []string{node}}
+			case kv.Version == e.Version:
+				e.Nodes = append(e.Nodes, node)
+			}
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "kvctl: scan %s: %v; results may be incomplete\n", node, err)
+		}
+	}
+
+	keys := make([]string, 0, len(newest))
+	for k := range newest {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if *limit > 0 && len(keys) > *limit {
+		keys = keys[:*limit]
+	}
+	entries := make([]entry, len(keys))
+	rows := make([][]string, len(keys))
+	for i, k := range keys {
+		entries[i] = *newest[k]
+		rows[i] = append(entries[i].row(), strings.Join(entries[i].Nodes, ","))
+	}
+	o.print(entries, []string{"KEY", "VALUE", "VERSION", "NODES"}, rows)
+}
+
+// scanNode pages through every key stored on node.
+func scanNode(ctx context.Context, node string, fn func(*proto.KeyValue)) error {
+	conn, err := grpc.Dial(node, grpc.WithInsecure())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	client := proto.NewKVClient(conn)
+	req := &proto.ScanRequest{StartHash: 0, EndHash: math.MaxUint32}
+	for {
+		reply, err := client.Scan(ctx, req)
+		if err != nil {
+			return err
+		}
+		for _, kv := range reply.Entries {
+			fn(kv)
+		}
+		if reply.NextCursor == "" {
+			return nil
+		}
+		req.Cursor = reply.NextCursor
+	}
+}
@@ -0,0 +1,203 @@
+// cmd/kvctl/main.go
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/metadata"
+)
+
+const usage = `kvctl is the operator CLI of the cluster.
+
+Data, through a proxy:
+  kvctl get <key>
+  kvctl put <key> <value>
+  kvctl delete <key>
+  kvctl scan [-prefix p] [-limit n]
+
+Ring configuration:
+  kvctl ring show
+  kvctl ring add <node> [-region r] [-weight w] [-now]
+  kvctl ring remove <node> [-now]
+  kvctl ring weight <node> <weight> [-now]
+
+Per-key replicas:
+  kvctl replicas show <key>
+  kvctl replicas set <key> <node,node,...> [-no-copy]
+  kvctl replicas reset <key>
+
+Storage nodes:
+  kvctl nodes status
+  kvctl nodes health
+
+Every command takes -etcd, -proxy and -o table|json; run one with -h for
+its other flags.
+`
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("kvctl: ")
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cmd, args := os.Args[1], os.Args[2:]
+	switch cmd {
+	case "get":
+		getCmd(args)
+	case "put":
+		putCmd(args)
+	case "delete":
+		deleteCmd(args)
+	case "scan":
+		scanCmd(args)
+	case "ring":
+		group(cmd, args, map[string]func([]string){
+			"show":   ringShowCmd,
+			"add":    ringAddCmd,
+			"remove": ringRemoveCmd,
+			"weight": ringWeightCmd,
+		})
+	case "replicas":
+		group(cmd, args, map[string]func([]string){
+			"show":  replicasShowCmd,
+			"set":   replicasSetCmd,
+			"reset": replicasResetCmd,
+		})
+	case "nodes":
+		group(cmd, args, map[string]func([]string){
+			"status": nodesStatusCmd,
+			"health": nodesHealthCmd,
+		})
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+}
+
+// group dispatches the subcommands of a command group.
+func group(name string, args []string, cmds map[string]func([]string)) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "%s needs a subcommand\n\n%s", name, usage)
+		os.Exit(2)
+	}
+	fn, ok := cmds[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", name+" "+args[0], usage)
+		os.Exit(2)
+	}
+	fn(args[1:])
+}
+
+// options are the flags every command takes.
+type options struct {
+	etcd   string
+	proxy  string
+	output string
+	vnodes int
+	R      int
+}
+
+// newFlags returns the flag set of a command with the common flags.
+func newFlags(name string) (*flag.FlagSet, *options) {
+	o := &options{}
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.StringVar(&o.etcd, "etcd", "localhost:2379", "comma-separated etcd endpoints")
+	fs.StringVar(&o.proxy, "proxy", "localhost:8080", "proxy address for data commands")
+	fs.StringVar(&o.output, "o", "table", "output format: table or json")
+	fs.IntVar(&o.vnodes, "vnodes", 100, "number of virtual nodes per physical node, as on the proxies")
+	fs.IntVar(&o.R, "replicas", 3, "replication factor, as on the proxies")
+	return fs, o
+}
+
+// parse parses args and checks that exactly n positional arguments remain.
+func parse(fs *flag.FlagSet, o *options, args []string, n int, names string) []string {
+	fs.Parse(args)
+	if o.output != "table" && o.output != "json" {
+		log.Fatalf("%s: -o must be table or json", fs.Name())
+	}
+	// allow flags after the positional arguments too
+	var pos []string
+	rest := fs.Args()
+	for len(rest) > 0 {
+		pos = append(pos, rest[0])
+		fs.Parse(rest[1:])
+		rest = fs.Args()
+	}
+	if len(pos) != n {
+		log.Fatalf("usage: kvctl %s %s", fs.Name(), names)
+	}
+	return pos
+}
+
+// print writes v as indented JSON, or rows under header as a table.
+func (o *options) print(v interface{}, header []string, rows [][]string) {
+	if o.output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			log.Fatalf("encode: %v", err)
+		}
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+}
+
+// metadata connects to etcd.
+func (o *options) metadata() *metadata.Client {
+	md, err := metadata.NewClient(strings.Split(o.etcd, ","))
+	if err != nil {
+		log.Fatalf("failed to connect to etcd: %v", err)
+	}
+	return md
+}
+
+// ring loads the cluster's routing state from etcd into a ring, as a proxy
+// sees it.
+func (o *options) ring(md *metadata.Client) *hashring.Ring {
+	ring := hashring.New(o.vnodes)
+	md.WatchRingConfig(ring.Update)
+	md.WatchReplicas(ring.UpdateReplicas)
+	md.WatchPolicies(ring.UpdatePolicy)
+	md.WatchResidency(ring.UpdateResidency)
+	synced(md)
+	return ring
+}
+
+// synced waits until every watch of md has delivered the initial state.
+func synced(md *metadata.Client) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		ready := true
+		for _, w := range md.Watches() {
+			if !w.Healthy {
+				ready = false
+				if time.Now().After(deadline) {
+					if w.Err == "" {
+						w.Err = "timed out"
+					}
+					log.Fatalf("load %s: %s", w.Key, w.Err)
+				}
+			}
+		}
+		if ready {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,158 @@
+// cmd/kvctl/nodes.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/metadata"
+)
+
+// nodeStatus is a storage node as "nodes status" prints it: a ring member,
+// a registered server, or both.
+type nodeStatus struct {
+	Addr       string  `json:"addr"`
+	ID         string  `json:"id,omitempty"`
+	Region     string  `json:"region,omitempty"`
+	Zone       string  `json:"zone,omitempty"`
+	Capacity   float64 `json:"capacity,omitempty"`
+	InRing     bool    `json:"in_ring"`
+	Registered bool    `json:"registered"`
+}
+
+// nodes lists the ring members and registered servers by address.
+func nodes(md *metadata.Client) []nodeStatus {
+	var mu sync.Mutex
+	registered := make(map[string]metadata.NodeInfo)
+	md.WatchNodes(func(id string, value []byte) {
+		var info metadata.NodeInfo
+		if len(value) == 0 || json.Unmarshal(value, &info) != nil {
+			return
+		}
+		mu.Lock()
+		registered[info.Addr] = info
+		mu.Unlock()
+	})
+	synced(md)
+	cfg, _ := readConfig(md)
+
+	mu.Lock()
+	defer mu.Unlock()
+	byAddr := make(map[string]*nodeStatus)
+	for _, node := range cfg.Nodes {
+		byAddr[node] = &nodeStatus{Addr: node, Region: cfg.Regions[node], InRing: true}
+	}
+	for addr, info := range registered {
+		n, ok := byAddr[addr]
+		if !ok {
+			n = &nodeStatus{Addr: addr}
+			byAddr[addr] = n
+		}
+		n.ID, n.Zone, n.Capacity, n.Registered = info.ID, info.Zone, info.Capacity, true
+		if n.Region == "" {
+			n.Region = info.Region
+		}
+	}
+	out := make([]nodeStatus, 0, len(byAddr))
+	for _, n := range byAddr {
+		out = append(out, *n)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Addr < out[j].Addr })
+	return out
+}
+
+// nodesStatusCmd lists the ring members and the registered servers.
+//
+//	kvctl nodes status
+func nodesStatusCmd(args []string) {
+	fs, o := newFlags("nodes status")
+	parse(fs, o, args, 0, "")
+	all := nodes(o.metadata())
+	rows := make([][]string, len(all))
+	for i, n := range all {
+		capacity := ""
+		if n.Capacity != 0 {
+			capacity = strconv.FormatFloat(n.Capacity, 'g', -1, 64)
+		}
+		rows[i] = []string{n.Addr, n.ID, n.Region, n.Zone, capacity, yesNo(n.InRing), yesNo(n.Registered)}
+	}
+	o.print(all, []string{"ADDR", "ID", "REGION", "ZONE", "CAPACITY", "IN RING", "REGISTERED"}, rows)
+}
+
+// nodeHealth is the result of health-checking a node.
+type nodeHealth struct {
+	Addr    string  `json:"addr"`
+	Status  string  `json:"status"`
+	Latency float64 `json:"latency_ms,omitempty"`
+	Err     string  `json:"error,omitempty"`
+}
+
+// nodesHealthCmd health-checks every ring member and registered server, as
+// the proxies do, and exits non-zero if any is not serving.
+//
+//	kvctl nodes health [-timeout d]
+func nodesHealthCmd(args []string) {
+	fs, o := newFlags("nodes health")
+	timeout := fs.Duration("timeout", 2*time.Second, "per-node check timeout")
+	parse(fs, o, args, 0, "[-timeout d]")
+	all := nodes(o.metadata())
+
+	results := make([]nodeHealth, len(all))
+	var wg sync.WaitGroup
+	for i, n := range all {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = check(n.Addr, *timeout)
+		}()
+	}
+	wg.Wait()
+
+	healthy := true
+	rows := make([][]string, len(results))
+	for i, r := range results {
+		latency := ""
+		if r.Latency > 0 {
+			latency = strconv.FormatFloat(r.Latency, 'f', 1, 64) + "ms"
+		}
+		rows[i] = []string{r.Addr, r.Status, latency, r.Err}
+		healthy = healthy && r.Status == healthpb.HealthCheckResponse_SERVING.String()
+	}
+	o.print(results, []string{"ADDR", "STATUS", "LATENCY", "ERROR"}, rows)
+	if !healthy {
+		os.Exit(1)
+	}
+}
+
+// check runs one gRPC health check of addr.
+func check(addr string, timeout time.Duration) nodeHealth {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	start := time.Now()
+	conn, err := grpc.DialContext(ctx, addr, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		return nodeHealth{Addr: addr, Status: "UNREACHABLE", Err: err.Error()}
+	}
+	defer conn.Close()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return nodeHealth{Addr: addr, Status: "UNKNOWN", Err: err.Error()}
+	}
+	ms := float64(time.Since(start).Microseconds()) / 1000
+	return nodeHealth{Addr: addr, Status: resp.Status.String(), Latency: ms}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
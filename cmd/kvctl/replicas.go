@@ -0,0 +1,95 @@
+// cmd/kvctl/replicas.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/replication"
+)
+
+// replicasShowCmd prints the replicas the proxies route key to.
+//
+//	kvctl replicas show <key>
+func replicasShowCmd(args []string) {
+	fs, o := newFlags("replicas show")
+	key := parse(fs, o, args, 1, "<key>")[0]
+	md := o.metadata()
+	ring := o.ring(md)
+
+	replicas := ring.GetReplicaList(key, o.R)
+	source := "ring"
+	if _, ok := ring.Override(key); ok {
+		source = "override"
+	} else if name, _, ok := ring.Policy(key); ok {
+		source = "policy " + name
+	}
+	_, rev, err := md.Replicas(key)
+	if err != nil {
+		log.Fatalf("read replicas of %s: %v", key, err)
+	}
+	o.print(struct {
+		Key      string   `json:"key"`
+		Replicas []string `json:"replicas"`
+		Source   string   `json:"source"`
+		Epoch    uint64   `json:"epoch"`
+		Revision int64    `json:"revision,omitempty"` // of the override
+	}{key, replicas, source, ring.Epoch(), rev},
+		[]string{"KEY", "REPLICAS", "SOURCE", "EPOCH"},
+		[][]string{{key, strings.Join(replicas, ","), source, strconv.FormatUint(ring.Epoch(), 10)}})
+}
+
+// replicasSetCmd overrides the replicas of key. Unless -no-copy is given,
+// the key is moved like the placement manager moves it: written to both
+// lists while it is copied to the new replicas, then switched over.
+//
+//	kvctl replicas set <key> <node,node,...> [-no-copy]
+func replicasSetCmd(args []string) {
+	fs, o := newFlags("replicas set")
+	noCopy := fs.Bool("no-copy", false, "only change routing, without copying the key to the new replicas")
+	pos := parse(fs, o, args, 2, "<key> <node,node,...> [-no-copy]")
+	key, to := pos[0], strings.Split(pos[1], ",")
+	md := o.metadata()
+	ring := o.ring(md)
+
+	if err := ring.CheckResidency(key, to); err != nil {
+		log.Fatalf("set replicas of %s: %v", key, err)
+	}
+	if *noCopy {
+		_, rev, err := md.Replicas(key)
+		if err != nil {
+			log.Fatalf("read replicas of %s: %v", key, err)
+		}
+		if _, err := md.SetReplicas(key, to, rev); err != nil {
+			log.Fatalf("set replicas of %s: %v", key, err)
+		}
+		fmt.Printf("%s routed to %s\n", key, pos[1])
+		return
+	}
+
+	from := ring.GetReplicaList(key, o.R)
+	mover := replication.NewMover(ring, md, o.R)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	if err := mover.Move(ctx, key, from, to); err != nil {
+		log.Fatalf("set replicas of %s: %v", key, err)
+	}
+	fmt.Printf("%s moved from %s to %s; copies on removed nodes are left in place\n", key, strings.Join(from, ","), pos[1])
+}
+
+// replicasResetCmd deletes the override of key, returning it to its ring
+// placement. Like "manager reset", it only changes routing.
+//
+//	kvctl replicas reset <key>
+func replicasResetCmd(args []string) {
+	fs, o := newFlags("replicas reset")
+	key := parse(fs, o, args, 1, "<key>")[0]
+	if err := o.metadata().ClearReplicas(key); err != nil {
+		log.Fatalf("reset %s: %v", key, err)
+	}
+	fmt.Printf("reset %s\n", key)
+}
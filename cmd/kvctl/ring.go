@@ -0,0 +1,207 @@
+// cmd/kvctl/ring.go
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/metadata"
+)
+
+// ringNode is a ring member as "ring show" prints it.
+type ringNode struct {
+	Node   string  `json:"node"`
+	Region string  `json:"region,omitempty"`
+	Weight float64 `json:"weight"`
+	// Share is the node's expected share of primaries.
+	Share float64 `json:"share"`
+}
+
+// ringShowCmd prints the ring configuration and each node's share of keys.
+//
+//	kvctl ring show
+func ringShowCmd(args []string) {
+	fs, o := newFlags("ring show")
+	parse(fs, o, args, 0, "")
+	cfg, rev := readConfig(o.metadata())
+	if cfg.VNodes == 0 {
+		cfg.VNodes = o.vnodes
+	}
+	p, err := hashring.NewPlacement(cfg)
+	if err != nil {
+		log.Fatalf("ring config: %v", err)
+	}
+	shares := hashring.Shares(p)
+
+	nodes := make([]ringNode, len(cfg.Nodes))
+	rows := make([][]string, len(cfg.Nodes))
+	for i, node := range cfg.Nodes {
+		w := 1.0
+		if v, ok := cfg.Weights[node]; ok {
+			w = v
+		}
+		nodes[i] = ringNode{Node: node, Region: cfg.Regions[node], Weight: w, Share: shares[node]}
+		rows[i] = []string{node, cfg.Regions[node], strconv.FormatFloat(w, 'g', -1, 64), fmt.Sprintf("%.1f%%", 100*shares[node])}
+	}
+	algorithm := cfg.Algorithm
+	if algorithm == "" {
+		algorithm = hashring.AlgorithmConsistent
+	}
+	if o.output == "table" {
+		fmt.Printf("algorithm %s, epoch %d, revision %d\n\n", algorithm, cfg.Epoch, rev)
+	}
+	o.print(struct {
+		Algorithm string     `json:"algorithm"`
+		Epoch     uint64     `json:"epoch"`
+		Revision  int64      `json:"revision"`
+		Nodes     []ringNode `json:"nodes"`
+	}{algorithm, cfg.Epoch, rev, nodes}, []string{"NODE", "REGION", "WEIGHT", "SHARE"}, rows)
+}
+
+// ringAddCmd adds a node to the ring.
+//
+//	kvctl ring add <node> [-region r] [-weight w] [-now]
+func ringAddCmd(args []string) {
+	fs, o := newFlags("ring add")
+	region := fs.String("region", "", "region the node runs in")
+	weight := fs.Float64("weight", 1, "the node's relative capacity")
+	now := nowFlag(fs)
+	node := parse(fs, o, args, 1, "<node> [-region r] [-weight w] [-now]")[0]
+
+	changeConfig(o, *now, func(cfg *hashring.Config) error {
+		for _, n := range cfg.Nodes {
+			if n == node {
+				return fmt.Errorf("%s is already in the ring", node)
+			}
+		}
+		cfg.Nodes = append(cfg.Nodes, node)
+		if *region != "" {
+			if cfg.Regions == nil {
+				cfg.Regions = make(map[string]string)
+			}
+			cfg.Regions[node] = *region
+		}
+		return setWeight(cfg, node, *weight)
+	})
+}
+
+// ringRemoveCmd removes a node from the ring.
+//
+//	kvctl ring remove <node> [-now]
+func ringRemoveCmd(args []string) {
+	fs, o := newFlags("ring remove")
+	now := nowFlag(fs)
+	node := parse(fs, o, args, 1, "<node> [-now]")[0]
+
+	changeConfig(o, *now, func(cfg *hashring.Config) error {
+		nodes := make([]string, 0, len(cfg.Nodes))
+		for _, n := range cfg.Nodes {
+			if n != node {
+				nodes = append(nodes, n)
+			}
+		}
+		if len(nodes) == len(cfg.Nodes) {
+			return fmt.Errorf("%s is not in the ring", node)
+		}
+		cfg.Nodes = nodes
+		delete(cfg.Regions, node)
+		delete(cfg.Weights, node)
+		return nil
+	})
+}
+
+// ringWeightCmd sets a node's weight.
+//
+//	kvctl ring weight <node> <weight> [-now]
+func ringWeightCmd(args []string) {
+	fs, o := newFlags("ring weight")
+	now := nowFlag(fs)
+	pos := parse(fs, o, args, 2, "<node> <weight> [-now]")
+	w, err := strconv.ParseFloat(pos[1], 64)
+	if err != nil {
+		log.Fatalf("weight %q: %v", pos[1], err)
+	}
+
+	changeConfig(o, *now, func(cfg *hashring.Config) error {
+		for _, n := range cfg.Nodes {
+			if n == pos[0] {
+				return setWeight(cfg, n, w)
+			}
+		}
+		return fmt.Errorf("%s is not in the ring", pos[0])
+	})
+}
+
+// nowFlag registers the -now flag of the ring changes.
+func nowFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("now", false, "publish the configuration at once instead of proposing it to the rebalancer, which copies the data first")
+}
+
+// setWeight sets node's weight, leaving the default of 1 implicit.
+func setWeight(cfg *hashring.Config, node string, w float64) error {
+	if !(w > 0) {
+		return fmt.Errorf("weight must be positive, got %v", w)
+	}
+	if w == 1 {
+		delete(cfg.Weights, node)
+		return nil
+	}
+	if cfg.Weights == nil {
+		cfg.Weights = make(map[string]float64)
+	}
+	cfg.Weights[node] = w
+	return nil
+}
+
+// readConfig returns the current ring configuration and its revision.
+func readConfig(md metadata.Store) (hashring.Config, int64) {
+	raw, rev, err := md.RingConfig()
+	if err != nil {
+		log.Fatalf("read ring config: %v", err)
+	}
+	var cfg hashring.Config
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			log.Fatalf("decode ring config: %v", err)
+		}
+	}
+	return cfg, rev
+}
+
+// changeConfig applies change to the current ring configuration and
+// proposes the result to the rebalancer, or with now publishes it
+// directly, provided nobody changed the configuration in between.
+func changeConfig(o *options, now bool, change func(*hashring.Config) error) {
+	md := o.metadata()
+	cfg, rev := readConfig(md)
+	if cfg.VNodes == 0 {
+		cfg.VNodes = o.vnodes
+	}
+	if err := change(&cfg); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := hashring.NewPlacement(cfg); err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	buf, err := json.Marshal(cfg)
+	if err != nil {
+		log.Fatalf("encode ring config: %v", err)
+	}
+
+	if !now {
+		if err := md.ProposeRingConfig(buf); err != nil {
+			log.Fatalf("propose ring config: %v", err)
+		}
+		fmt.Println("proposed; the rebalancer publishes it once the data has moved")
+		return
+	}
+	if _, err := md.SetRingConfig(buf, rev); err != nil {
+		log.Fatalf("publish ring config: %v", err)
+	}
+	cfg, _ = readConfig(md)
+	fmt.Printf("published epoch %d\n", cfg.Epoch)
+}
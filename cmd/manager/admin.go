@@ -0,0 +1,153 @@
+// cmd/manager/admin.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/metadata"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/proto"
+)
+
+// planCmd fetches a dry-run plan from the manager and prints it as JSON.
+//
+//	manager plan [-addr host:port | -etcd endpoints] [-o plan.json]
+func planCmd(args []string) {
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	addr := fs.String("addr", "", "manager admin address (default: the elected leader)")
+	etcdEndpoints := fs.String("etcd", "localhost:2379", "comma-separated etcd endpoints, used to find the leader")
+	out := fs.String("o", "", "write the plan to this file instead of stdout")
+	fs.Parse(args)
+
+	client, closeFn := adminClient(*addr, *etcdEndpoints)
+	defer closeFn()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	reply, err := client.Plan(ctx, &proto.PlanRequest{})
+	if err != nil {
+		log.Fatalf("plan: %v", err)
+	}
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, []byte(reply.PlanJson), "", "  "); err != nil {
+		log.Fatalf("decode plan: %v", err)
+	}
+	pretty.WriteByte('\n')
+	if *out == "" {
+		os.Stdout.Write(pretty.Bytes())
+		return
+	}
+	if err := os.WriteFile(*out, pretty.Bytes(), 0o644); err != nil {
+		log.Fatalf("write %s: %v", *out, err)
+	}
+	fmt.Printf("plan %s written to %s\n", reply.Id, *out)
+}
+
+// applyCmd approves a plan previously returned by planCmd.
+//
+//	manager apply -id <plan id> [-keys k1,k2] [-addr host:port | -etcd endpoints]
+func applyCmd(args []string) {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	addr := fs.String("addr", "", "manager admin address (default: the elected leader)")
+	etcdEndpoints := fs.String("etcd", "localhost:2379", "comma-separated etcd endpoints, used to find the leader")
+	id := fs.String("id", "", "plan ID to apply (required)")
+	keys := fs.String("keys", "", "comma-separated keys to apply (default: the whole plan)")
+	fs.Parse(args)
+	if *id == "" {
+		log.Fatalf("apply: -id is required")
+	}
+
+	req := &proto.ApplyPlanRequest{Id: *id}
+	if *keys != "" {
+		req.Keys = strings.Split(*keys, ",")
+	}
+	client, closeFn := adminClient(*addr, *etcdEndpoints)
+	defer closeFn()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	reply, err := client.ApplyPlan(ctx, req)
+	if err != nil {
+		log.Fatalf("apply: %v", err)
+	}
+
+	for _, key := range reply.Applied {
+		fmt.Printf("applied %s\n", key)
+	}
+	failed := make([]string, 0, len(reply.Failed))
+	for key := range reply.Failed {
+		failed = append(failed, key)
+	}
+	sort.Strings(failed)
+	for _, key := range failed {
+		fmt.Printf("failed  %s: %s\n", key, reply.Failed[key])
+	}
+	if len(failed) > 0 {
+		os.Exit(1)
+	}
+}
+
+// resetCmd deletes replica overrides so keys return to their default ring
+// placement.
+//
+//	manager reset [-etcd endpoints] (-keys k1,k2 | -prefix p | -all)
+func resetCmd(args []string) {
+	fs := flag.NewFlagSet("reset", flag.ExitOnError)
+	etcdEndpoints := fs.String("etcd", "localhost:2379", "comma-separated etcd endpoints")
+	keys := fs.String("keys", "", "comma-separated keys to reset")
+	prefix := fs.String("prefix", "", "reset every key with this prefix")
+	all := fs.Bool("all", false, "reset every key")
+	fs.Parse(args)
+	if (*keys != "") == (*prefix != "" || *all) || (*prefix != "" && *all) {
+		log.Fatalf("reset: give exactly one of -keys, -prefix and -all")
+	}
+
+	md, err := metadata.NewClient(strings.Split(*etcdEndpoints, ","))
+	if err != nil {
+		log.Fatalf("failed to connect to etcd: %v", err)
+	}
+	if *keys != "" {
+		for _, key := range strings.Split(*keys, ",") {
+			if err := md.ClearReplicas(key); err != nil {
+				log.Fatalf("reset %s: %v", key, err)
+			}
+			fmt.Printf("reset %s\n", key)
+		}
+		return
+	}
+	n, err := md.ClearReplicasWithPrefix(*prefix)
+	if err != nil {
+		log.Fatalf("reset: %v", err)
+	}
+	fmt.Printf("reset %d keys\n", n)
+}
+
+// adminClient dials addr, or the current leader if addr is empty.
+func adminClient(addr, etcdEndpoints string) (proto.AdminClient, func()) {
+	if addr == "" {
+		md, err := metadata.NewClient(strings.Split(etcdEndpoints, ","))
+		if err != nil {
+			log.Fatalf("failed to connect to etcd: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		addr, err = md.Leader(ctx, electionName)
+		cancel()
+		if err != nil {
+			log.Fatalf("find leader: %v", err)
+		}
+	}
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		log.Fatalf("dial %s: %v", addr, err)
+	}
+	return proto.NewAdminClient(conn), func() { conn.Close() }
+}
@@ -0,0 +1,219 @@
+// cmd/manager/main.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/audit"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/membership"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/metadata"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/replication"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/telemetry"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/proto"
+)
+
+// electionName is the etcd election all manager instances campaign in; its
+// leader is where proxies send telemetry.
+const electionName = telemetry.ManagerElection
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "plan":
+			planCmd(os.Args[2:])
+			return
+		case "apply":
+			applyCmd(os.Args[2:])
+			return
+		case "reset":
+			resetCmd(os.Args[2:])
+			return
+		case "run":
+			os.Args = append(os.Args[:1], os.Args[2:]...)
+		}
+	}
+	run()
+}
+
+// run is the manager daemon: it serves telemetry and the admin API and,
+// while leader, runs the placement loop and the rebalancer.
+func run() {
+	etcdEndpoints := flag.String("etcd", "localhost:2379", "comma-separated etcd endpoints")
+	listenAddr := flag.String("listen", ":7070", "telemetry and admin listen address")
+	advertise := flag.String("advertise", "", "address proxies reach this instance at (default: hostname + listen port)")
+	interval := flag.Duration("interval", 30*time.Second, "placement evaluation interval")
+	vnodes := flag.Int("vnodes", 100, "number of virtual nodes per physical node")
+	R := flag.Int("replicas", 3, "replication factor")
+	model := flag.String("model", "cost", "placement policy: cost (latency/cost model) or none (collect telemetry only)")
+	costConfig := flag.String("cost-config", "", "JSON file with the cost model's latency matrix and prices (default: 100ms between regions, no costs)")
+	rebalanceBps := flag.Float64("rebalance-bytes-per-sec", 0, "throttle for ring rebalancing copies (0 is unlimited)")
+	primaryMargin := flag.Float64("primary-margin", 1.5, "move a key's primary to a region generating this many times its primary region's traffic (0 disables)")
+	primaryCooldown := flag.Duration("primary-cooldown", time.Hour, "minimum time between two primary moves of a key")
+	maxChanges := flag.Int("max-changes", 100, "placement changes applied per interval, highest priority first (0 is unlimited)")
+	maxMoves := flag.Int("max-moves", 8, "concurrent key moves (0 is unlimited)")
+	maxNodeMoves := flag.Int("max-node-moves", 2, "concurrent key moves per node (0 is unlimited)")
+	moveBps := flag.Float64("move-bytes-per-sec", 0, "copy bandwidth of all key moves (0 is unlimited)")
+	nodeMoveBps := flag.Float64("node-move-bytes-per-sec", 0, "copy bandwidth of key moves per node (0 is unlimited)")
+	manageMembership := flag.Bool("membership", false, "derive /ring/config from the servers registered under /nodes/")
+	removeDelay := flag.Duration("node-remove-delay", time.Minute, "how long a server must stay unregistered before it leaves the ring")
+	auditLog := flag.String("audit-log", "", "append residency violations to this file (default: stderr)")
+	dryRun := flag.Bool("dry-run", false, "only log placement plans as JSON; never change /replicas/")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	md, err := metadata.NewClient(strings.Split(*etcdEndpoints, ","))
+	if err != nil {
+		log.Fatalf("failed to connect to etcd: %v", err)
+	}
+	if *auditLog != "" {
+		f, err := os.OpenFile(*auditLog, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			log.Fatalf("open audit log: %v", err)
+		}
+		defer f.Close()
+		audit.SetOutput(f)
+	}
+	ring := hashring.New(*vnodes)
+	ring.OnViolation = func(v *hashring.ResidencyError) {
+		audit.Record("ring", "residency_violation", v)
+	}
+	md.WatchRingConfig(ring.Update)
+	md.WatchReplicas(ring.UpdateReplicas)
+	md.WatchPolicies(ring.UpdatePolicy)
+	md.WatchResidency(ring.UpdateResidency)
+
+	policy, err := newPolicy(*model, *costConfig, ring)
+	if err != nil {
+		log.Fatalf("policy: %v", err)
+	}
+	mgr := replication.NewManager(ring, md, *R, policy)
+	mgr.PrimaryMargin = *primaryMargin
+	mgr.PrimaryCooldown = *primaryCooldown
+	mgr.MaxChangesPerRound = *maxChanges
+	mgr.MaxConcurrentMoves = *maxMoves
+	mgr.MaxMovesPerNode = *maxNodeMoves
+	mgr.MoveBytesPerSec = *moveBps
+	mgr.NodeMoveBytesPerSec = *nodeMoveBps
+	mgr.DryRun = *dryRun
+	if *dryRun {
+		mgr.OnPlan = func(p *replication.Plan) {
+			if len(p.Changes) == 0 {
+				return
+			}
+			buf, _ := json.Marshal(p)
+			log.Printf("dry-run plan: %s", buf)
+		}
+	}
+	members := membership.NewController(md, ring)
+	members.RemoveDelay = *removeDelay
+	rebalancer := replication.NewRebalancer(ring, md, *R, *rebalanceBps)
+	rebalancer.OnProgress = func(p replication.Progress) {
+		log.Printf("rebalance: %d/%d transfers, %d keys, %d bytes", p.TransfersDone, p.Transfers, p.Keys, p.Bytes)
+	}
+
+	// proxies report telemetry to the leader, but every instance serves it so
+	// reports sent just before a leadership change are not refused
+	lis, err := net.Listen("tcp", *listenAddr)
+	if err != nil {
+		log.Fatalf("failed to listen on %s: %v", *listenAddr, err)
+	}
+	grpcServer := grpc.NewServer()
+	proto.RegisterTelemetryServer(grpcServer, telemetry.NewServer(mgr))
+	proto.RegisterAdminServer(grpcServer, replication.NewAdminService(mgr))
+	go func() {
+		if err := grpcServer.Serve(lis); err != nil {
+			log.Fatalf("gRPC serve error: %v", err)
+		}
+	}()
+	defer grpcServer.GracefulStop()
+
+	id := *advertise
+	if id == "" {
+		host, _ := os.Hostname()
+		id = host + ":" + portOf(lis.Addr())
+	}
+	log.Printf("Manager %s listening on %s, campaigning for leadership", id, *listenAddr)
+	for ctx.Err() == nil {
+		lead(ctx, md, id, func(leaderCtx context.Context) {
+			go rebalancer.Run(leaderCtx)
+			if *manageMembership {
+				go members.Run(leaderCtx)
+			}
+			mgr.Run(leaderCtx, *interval)
+		})
+	}
+}
+
+// lead campaigns until this instance is leader, runs work while leadership
+// lasts, and returns once it is lost or ctx is done.
+func lead(ctx context.Context, md metadata.Store, id string, work func(context.Context)) {
+	leadership, err := md.Campaign(ctx, electionName, id)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("campaign error: %v", err)
+			time.Sleep(time.Second)
+		}
+		return
+	}
+	log.Printf("elected leader")
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		work(leaderCtx)
+	}()
+	select {
+	case <-ctx.Done():
+	case <-leadership.Done():
+		log.Printf("leadership lost, standing by")
+	}
+	cancel()
+	<-done
+
+	resignCtx, cancelResign := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelResign()
+	if err := leadership.Resign(resignCtx); err != nil && ctx.Err() == nil {
+		log.Printf("resign error: %v", err)
+	}
+}
+
+// newPolicy builds the placement policy selected on the command line.
+func newPolicy(model, costConfig string, ring *hashring.Ring) (replication.Policy, error) {
+	switch model {
+	case "cost":
+		if costConfig == "" {
+			log.Printf("no -cost-config, using a uniform cost model")
+			return replication.NewCostModel(ring, replication.DefaultCostModelConfig), nil
+		}
+		cfg, err := replication.LoadCostModelConfig(costConfig)
+		if err != nil {
+			return nil, fmt.Errorf("load %s: %w", costConfig, err)
+		}
+		return replication.NewCostModel(ring, cfg), nil
+	case "none":
+		return replication.DecisionFunc(func(key, node string) (bool, float64) { return false, 0 }), nil
+	default:
+		return nil, fmt.Errorf("unknown model %q", model)
+	}
+}
+
+func portOf(addr net.Addr) string {
+	_, port, _ := net.SplitHostPort(addr.String())
+	return port
+}
@@ -2,18 +2,22 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	"net"
+	"os"
 	"strings"
-
-	// "time"
+	"time"
 
 	"google.golang.org/grpc"
 
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/audit"
 	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/health"
 	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/metadata"
 	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/proxy"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/telemetry"
 	"github.com/ksharma120497/adaptive-geo-distributed-database/proto"
 )
 
@@ -23,6 +27,15 @@ func main() {
 	listenAddr := flag.String("listen", ":8080", "proxy listen address")
 	vnodes := flag.Int("vnodes", 100, "number of virtual nodes per physical node")
 	R := flag.Int("replicas", 3, "replication factor")
+	region := flag.String("region", "", "region this proxy runs in, used for clients that do not send one")
+	managerAddr := flag.String("manager", "", "placement manager address for access telemetry, \"leader\" for whichever manager is elected, or empty to disable it")
+	telemetryInterval := flag.Duration("telemetry-interval", 5*time.Second, "how often access counts are sent to the manager")
+	writeQuorum := flag.Int("write-quorum", 0, "acknowledgements a write needs (0 means every replica, counting substitutes)")
+	healthInterval := flag.Duration("health-interval", 2*time.Second, "how often storage nodes are health-checked")
+	errorThreshold := flag.Float64("error-threshold", 0.5, "request error rate above which a node is considered down")
+	phiThreshold := flag.Float64("phi-threshold", 0, "phi-accrual suspicion above which a node is considered down (0 disables it)")
+	handoffInterval := flag.Duration("handoff-interval", 10*time.Second, "how often writes held by substitutes are handed back")
+	auditLog := flag.String("audit-log", "", "append residency violations to this file (default: stderr)")
 	flag.Parse()
 
 	// Create metadata client (it will connect to etcd internally)
@@ -31,10 +44,31 @@ func main() {
 		log.Fatalf("failed to connect to etcd: %v", err)
 	}
 
+	if *auditLog != "" {
+		f, err := os.OpenFile(*auditLog, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			log.Fatalf("open audit log: %v", err)
+		}
+		defer f.Close()
+		audit.SetOutput(f)
+	}
+
 	// Initialize consistent-hash ring
 	ring := hashring.New(*vnodes)
+	ring.OnViolation = func(v *hashring.ResidencyError) {
+		audit.Record("ring", "residency_violation", v)
+	}
 	md.WatchRingConfig(ring.Update)
 	md.WatchReplicas(ring.UpdateReplicas)
+	md.WatchPolicies(ring.UpdatePolicy)
+	md.WatchResidency(ring.UpdateResidency)
+
+	tracker := health.NewTracker()
+	tracker.Interval = *healthInterval
+	tracker.ErrorThreshold = *errorThreshold
+	tracker.PhiThreshold = *phiThreshold
+	ring.Healthy = tracker.Healthy
+	go tracker.Run(context.Background(), ring.AllNodes)
 
 	// Create and start gRPC server
 	lis, err := net.Listen("tcp", *listenAddr)
@@ -45,6 +79,21 @@ func main() {
 
 	// Register proxy service
 	svc := proxy.NewProxyServer(ring, md, *R)
+	svc.Region = *region
+	svc.Health = tracker
+	svc.WriteQuorum = *writeQuorum
+	go svc.RunHandoff(context.Background(), *handoffInterval)
+	if *managerAddr != "" {
+		host, _ := os.Hostname()
+		reporter := telemetry.NewReporter(host+*listenAddr, *managerAddr)
+		if *managerAddr == "leader" {
+			reporter.Resolve = func(ctx context.Context) (string, error) {
+				return md.Leader(ctx, telemetry.ManagerElection)
+			}
+		}
+		go reporter.Run(context.Background(), *telemetryInterval)
+		svc.Recorder = reporter
+	}
 	proto.RegisterKVServer(grpcServer, svc)
 
 	log.Printf("Proxy listening on %s (etcd endpoints: %s)", *listenAddr, *etcdEndpoints)
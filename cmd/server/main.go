@@ -2,23 +2,43 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
-	//   clientv3 "go.etcd.io/etcd/client/v3"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
 	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/kvstore"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/metadata"
 	"github.com/ksharma120497/adaptive-geo-distributed-database/proto"
 	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 func main() {
 	port := flag.Int("port", 50051, "gRPC port")
 	walPath := flag.String("wal", "wal.log", "WAL file path")
-	//   etcdEndpoints := flag.String("etcd", "localhost:2379", "etcd endpoints")
+	etcdEndpoints := flag.String("etcd", "", "comma-separated etcd endpoints to register with and follow the ring from (empty disables both)")
+	advertise := flag.String("advertise", "", "address proxies and peers reach this server at (default: hostname + port)")
+	id := flag.String("id", "", "node ID under /nodes/ (default: the advertised address)")
+	region := flag.String("region", "", "region this server runs in")
+	zone := flag.String("zone", "", "availability zone this server runs in")
+	capacity := flag.Float64("capacity", 1, "relative storage capacity")
+	vnodes := flag.Int("vnodes", 100, "number of virtual nodes per physical node, as on the proxies")
+	replicas := flag.Int("replicas", 3, "replication factor, as on the proxies")
+	tombstoneTTL := flag.Duration("tombstone-ttl", kvstore.DefaultTombstoneTTL, "how long deletes are remembered, so late copies of deleted keys are refused")
 	flag.Parse()
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Initialize KVStore
 	store, err := kvstore.NewWALStore(*walPath)
 	if err != nil {
@@ -27,8 +47,7 @@ func main() {
 	if err := store.Replay(); err != nil {
 		log.Fatalf("failed to replay WAL: %v", err)
 	}
-
-	// (Optional) etcd connection omitted since Service doesn't yet use it
+	go store.RunTombstoneGC(ctx, *tombstoneTTL)
 
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
 	if err != nil {
@@ -37,6 +56,68 @@ func main() {
 	grpcServer := grpc.NewServer()
 	svc := kvstore.NewService(store)
 	proto.RegisterKVServer(grpcServer, svc)
+	// Proxies health-check servers to route around failed ones.
+	healthSrv := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthSrv)
 	log.Printf("Server listening on :%d", *port)
+	go func() {
+		<-ctx.Done()
+		healthSrv.Shutdown()
+		grpcServer.GracefulStop()
+	}()
+
+	if *etcdEndpoints != "" {
+		md, err := metadata.NewClient(strings.Split(*etcdEndpoints, ","))
+		if err != nil {
+			log.Fatalf("failed to connect to etcd: %v", err)
+		}
+		info := metadata.NodeInfo{ID: *id, Addr: *advertise, Region: *region, Zone: *zone, Capacity: *capacity}
+		if info.Addr == "" {
+			host, _ := os.Hostname()
+			info.Addr = fmt.Sprintf("%s:%d", host, *port)
+		}
+		if info.ID == "" {
+			info.ID = info.Addr
+		}
+		go register(ctx, md, info)
+		// follow the ring to refuse requests for keys this node gave up
+		ring := hashring.New(*vnodes)
+		md.WatchRingConfig(ring.Update)
+		md.WatchReplicas(ring.UpdateReplicas)
+		md.WatchPolicies(ring.UpdatePolicy)
+		md.WatchResidency(ring.UpdateResidency)
+		svc.Ring = ring
+		svc.NodeID = info.Addr
+		svc.R = *replicas
+	}
 	grpcServer.Serve(lis)
 }
+
+// register keeps the node registered under /nodes/ until ctx is done, then
+// deregisters it so the membership controller can start its removal delay.
+func register(ctx context.Context, md metadata.Store, info metadata.NodeInfo) {
+	for ctx.Err() == nil {
+		lease, err := md.Register(ctx, info)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("register %s: %v", info.ID, err)
+				time.Sleep(2 * time.Second)
+			}
+			continue
+		}
+		log.Printf("registered as %s (%s, region %q)", info.ID, info.Addr, info.Region)
+		select {
+		case <-ctx.Done():
+		case <-lease.Done():
+			// the lease also ends with ctx
+			if ctx.Err() == nil {
+				log.Printf("registration lease of %s lost, registering again", info.ID)
+				continue
+			}
+		}
+		closeCtx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		lease.Close(closeCtx)
+		cancel()
+		return
+	}
+}
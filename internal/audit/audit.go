@@ -0,0 +1,42 @@
+// internal/audit/audit.go
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Entry is one line of the audit log.
+type Entry struct {
+	Time      time.Time   `json:"time"`
+	Component string      `json:"component"`
+	Event     string      `json:"event"`
+	Detail    interface{} `json:"detail"`
+}
+
+var (
+	mu  sync.Mutex
+	out io.Writer = os.Stderr
+)
+
+// SetOutput directs the audit log to w. It defaults to stderr.
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	out = w
+}
+
+// Record appends an event to the audit log as a JSON line. detail is
+// encoded as JSON.
+func Record(component, event string, detail interface{}) {
+	buf, err := json.Marshal(Entry{Time: time.Now().UTC(), Component: component, Event: event, Detail: detail})
+	if err != nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	out.Write(append(buf, '\n'))
+}
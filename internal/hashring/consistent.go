@@ -0,0 +1,60 @@
+package hashring
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+// consistent is the classic consistent-hash ring with virtual nodes. A
+// node's weight scales its number of virtual nodes.
+type consistent struct {
+	hashes []uint32          // sorted hashes of virtual nodes
+	nodes  map[uint32]string // hash -> physical node ID
+}
+
+func newConsistent(nodes []string, vnodes int, weights map[string]float64) *consistent {
+	c := &consistent{nodes: make(map[uint32]string, len(nodes)*vnodes)}
+	for _, node := range nodes {
+		n := int(math.Round(float64(vnodes) * weight(weights, node)))
+		if n < 1 && vnodes > 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			hash := hashKey(node + "#" + strconv.Itoa(i))
+			c.hashes = append(c.hashes, hash)
+			c.nodes[hash] = node
+		}
+	}
+	sort.Slice(c.hashes, func(i, j int) bool { return c.hashes[i] < c.hashes[j] })
+	return c
+}
+
+func (c *consistent) Algorithm() string { return AlgorithmConsistent }
+
+// Boundaries returns the start of every arc: a virtual node owns the hashes
+// after its predecessor up to and including its own.
+func (c *consistent) Boundaries() []uint32 {
+	b := make([]uint32, 0, len(c.hashes)+1)
+	b = append(b, 0)
+	for _, h := range c.hashes {
+		if h != math.MaxUint32 && h+1 != b[len(b)-1] {
+			b = append(b, h+1)
+		}
+	}
+	return b
+}
+
+// Owners walks the ring clockwise from h: primary + n-1 distinct successors.
+func (c *consistent) Owners(h uint32, n int) []string {
+	list := make([]string, 0, n)
+	idx := sort.Search(len(c.hashes), func(i int) bool { return c.hashes[i] >= h })
+	for i := 0; len(list) < n && i < len(c.hashes); i++ {
+		node := c.nodes[c.hashes[(idx+i)%len(c.hashes)]]
+		// avoid duplicates
+		if !contains(list, node) {
+			list = append(list, node)
+		}
+	}
+	return list
+}
@@ -0,0 +1,257 @@
+package hashring
+
+import (
+	"sort"
+	"strings"
+)
+
+// Range is an inclusive range of ring hashes.
+type Range struct {
+	Start uint32 `json:"start"`
+	End   uint32 `json:"end"`
+}
+
+// Contains reports whether h lies in the range.
+func (r Range) Contains(h uint32) bool {
+	return r.Start <= h && h <= r.End
+}
+
+// ContainsKey reports whether the key's ring hash lies in the range.
+func (r Range) ContainsKey(key string) bool {
+	return r.Contains(hashKey(key))
+}
+
+// FullRange covers the whole hash space.
+var FullRange = Range{Start: 0, End: ^uint32(0)}
+
+// RangeChange describes a hash range whose replica set differs between two
+// placements.
+type RangeChange struct {
+	Range   Range    `json:"range"`
+	From    []string `json:"from"`    // owners before the change, primary first
+	To      []string `json:"to"`      // owners after the change, primary first
+	Added   []string `json:"added"`   // nodes in To but not in From
+	Removed []string `json:"removed"` // nodes in From but not in To
+	// Prefix and Exclude, if set, limit the change to the keys in Range
+	// with Prefix and none of the Exclude prefixes.
+	Prefix  string   `json:"prefix,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// Diff returns, in hash order, the ranges whose R-replica set differs
+// between old and new. Adjacent ranges with identical changes are merged.
+// Ranges that only reorder their replicas are not reported, since no data
+// has to move for them.
+func Diff(old, new Placement, R int) []RangeChange {
+	bounds := mergeBoundaries(old.Boundaries(), new.Boundaries())
+
+	var changes []RangeChange
+	for i, start := range bounds {
+		end := ^uint32(0)
+		if i+1 < len(bounds) {
+			end = bounds[i+1] - 1
+		}
+		from, to := old.Owners(start, R), new.Owners(start, R)
+		if sameSet(from, to) {
+			continue
+		}
+		if n := len(changes); n > 0 {
+			last := &changes[n-1]
+			if last.Range.End+1 == start && equal(last.From, from) && equal(last.To, to) {
+				last.Range.End = end
+				continue
+			}
+		}
+		changes = append(changes, RangeChange{
+			Range:   Range{Start: start, End: end},
+			From:    from,
+			To:      to,
+			Added:   minus(to, from),
+			Removed: minus(from, to),
+		})
+	}
+	return changes
+}
+
+// DiffConfig is Diff between the ring's placement and the one cfg would
+// build, with owners resolved as GetReplicaList resolves them: under the
+// ring's placement policies and residency rules. Keys under a prefix policy
+// or residency rule can move differently from their neighbours on the ring,
+// so their changes are reported separately, scoped by Prefix and Exclude.
+// Per-key overrides do not depend on the ring and are left out.
+func (r *Ring) DiffConfig(cfg Config, R int) ([]RangeChange, error) {
+	next, err := NewPlacement(cfg)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	old := ownerView{placement: r.placement, regions: r.cfg.Regions, nodes: len(r.cfg.Nodes)}
+	cur := ownerView{placement: next, regions: cfg.Regions, nodes: len(cfg.Nodes)}
+
+	// range policies change the owners at their edges too
+	var edges []uint32
+	for _, p := range r.policyIndex.ranges {
+		edges = append(edges, p.Range.Start)
+		if p.Range.End != ^uint32(0) {
+			edges = append(edges, p.Range.End+1)
+		}
+	}
+	bounds := mergeBoundaries(mergeBoundaries(r.placement.Boundaries(), next.Boundaries()), edges)
+
+	var changes []RangeChange
+	for _, sc := range r.scopes() {
+		for i, start := range bounds {
+			end := ^uint32(0)
+			if i+1 < len(bounds) {
+				end = bounds[i+1] - 1
+			}
+			from, to := r.scopedOwners(old, sc, start, R), r.scopedOwners(cur, sc, start, R)
+			if sameSet(from, to) {
+				continue
+			}
+			if n := len(changes); n > 0 {
+				last := &changes[n-1]
+				if last.Prefix == sc.prefix && last.Range.End+1 == start && equal(last.From, from) && equal(last.To, to) {
+					last.Range.End = end
+					continue
+				}
+			}
+			changes = append(changes, RangeChange{
+				Range:   Range{Start: start, End: end},
+				From:    from,
+				To:      to,
+				Added:   minus(to, from),
+				Removed: minus(from, to),
+				Prefix:  sc.prefix,
+				Exclude: sc.exclude,
+			})
+		}
+	}
+	return changes, nil
+}
+
+// ownerView is a placement with the configuration details owner resolution
+// needs.
+type ownerView struct {
+	placement Placement
+	regions   map[string]string
+	nodes     int
+}
+
+// scope is the set of keys whose longest prefix among the placement policy
+// and residency rule prefixes is prefix: they share a policy, unless it is a
+// range policy, and residency rules.
+type scope struct {
+	prefix  string
+	exclude []string // longer prefixes, which have scopes of their own
+	policy  *namedPolicy
+	rules   []ResidencyRule
+}
+
+// scopes returns the scope of every policy and rule prefix, plus that of
+// keys matching none of them. Caller holds r.mu.
+func (r *Ring) scopes() []scope {
+	prefixes := map[string]bool{"": true}
+	for _, p := range r.policies {
+		if p.Prefix != "" {
+			prefixes[p.Prefix] = true
+		}
+	}
+	for _, rule := range r.residency {
+		prefixes[rule.Prefix] = true
+	}
+	sorted := make([]string, 0, len(prefixes))
+	for prefix := range prefixes {
+		sorted = append(sorted, prefix)
+	}
+	sort.Strings(sorted)
+
+	out := make([]scope, 0, len(sorted))
+	for _, prefix := range sorted {
+		sc := scope{prefix: prefix, policy: r.policyIndex.prefixAt(prefix)}
+		for _, other := range sorted {
+			if len(other) > len(prefix) && strings.HasPrefix(other, prefix) {
+				sc.exclude = append(sc.exclude, other)
+			}
+		}
+		for _, rule := range r.residency {
+			if strings.HasPrefix(prefix, rule.Prefix) {
+				sc.rules = append(sc.rules, rule)
+			}
+		}
+		out = append(out, sc)
+	}
+	return out
+}
+
+// scopedOwners is constrainedOwners for the keys of sc hashing to h, under
+// the placement of v. Caller holds r.mu.
+func (r *Ring) scopedOwners(v ownerView, sc scope, h uint32, R int) []string {
+	p := sc.policy
+	if p == nil {
+		p = r.policyIndex.rangeAt(h)
+	}
+	if p == nil && len(sc.rules) == 0 {
+		return v.placement.Owners(h, R)
+	}
+	if p != nil && p.Replicas > 0 {
+		R = p.Replicas
+	}
+	var out []string
+	for _, node := range v.placement.Owners(h, v.nodes) {
+		if len(out) == R {
+			break
+		}
+		if p != nil && !p.allows(node, v.regions) {
+			continue
+		}
+		resident := true
+		for _, rule := range sc.rules {
+			resident = resident && contains(rule.Regions, v.regions[node])
+		}
+		if resident {
+			out = append(out, node)
+		}
+	}
+	return out
+}
+
+// mergeBoundaries returns the sorted union of two boundary lists.
+func mergeBoundaries(a, b []uint32) []uint32 {
+	all := make([]uint32, 0, len(a)+len(b)+1)
+	all = append(all, 0)
+	all = append(all, a...)
+	all = append(all, b...)
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+	out := all[:1]
+	for _, h := range all[1:] {
+		if h != out[len(out)-1] {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// minus returns the elements of a that are not in b.
+func minus(a, b []string) []string {
+	var out []string
+	for _, v := range a {
+		if !contains(b, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
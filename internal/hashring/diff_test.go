@@ -0,0 +1,137 @@
+// internal/hashring/diff_test.go
+package hashring
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func testConfig(t *testing.T, nodes ...string) []byte {
+	t.Helper()
+	cfg := Config{Algorithm: AlgorithmConsistent, VNodes: 20, Nodes: nodes, Regions: make(map[string]string)}
+	for _, node := range nodes {
+		cfg.Regions[node] = strings.SplitN(node, "-", 2)[0]
+	}
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// constrainedRing returns a ring over raw with an "eu/" prefix policy, a
+// range policy and a residency rule for "eu/pii/".
+func constrainedRing(raw []byte) *Ring {
+	r := New(20)
+	r.Update(raw)
+	r.UpdatePolicy("eu", []byte(`{"prefix": "eu/", "regions": ["eu"], "replicas": 2}`))
+	r.UpdatePolicy("low", []byte(`{"range": {"start": 0, "end": 1000000000}, "regions": ["us"]}`))
+	r.UpdateResidency("pii", []byte(`{"prefix": "eu/pii/", "regions": ["eu"]}`))
+	return r
+}
+
+func TestDiffConfigFollowsPolicies(t *testing.T) {
+	before := testConfig(t, "eu-a", "eu-b", "us-a", "us-b", "us-c")
+	after := testConfig(t, "eu-a", "eu-b", "eu-c", "us-a", "us-b", "us-c")
+	old, next := constrainedRing(before), constrainedRing(after)
+	var cfg Config
+	json.Unmarshal(after, &cfg)
+
+	changes, err := old.DiffConfig(cfg, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	covering := func(key string) *RangeChange {
+		var found *RangeChange
+		for i, ch := range changes {
+			if !ch.Range.ContainsKey(key) || !strings.HasPrefix(key, ch.Prefix) {
+				continue
+			}
+			excluded := false
+			for _, prefix := range ch.Exclude {
+				excluded = excluded || strings.HasPrefix(key, prefix)
+			}
+			if !excluded {
+				if found != nil {
+					t.Fatalf("key %s covered by two changes: %+v and %+v", key, *found, ch)
+				}
+				found = &changes[i]
+			}
+		}
+		return found
+	}
+
+	moved := map[string]int{}
+	for i := 0; i < 3000; i++ {
+		key := []string{"eu/", "eu/pii/", "us/", ""}[i%4] + fmt.Sprint(i)
+		from, to := old.GetReplicaList(key, 3), next.GetReplicaList(key, 3)
+		ch := covering(key)
+		if sameSet(from, to) {
+			if ch != nil {
+				t.Fatalf("key %s keeps %v, but %+v moves it", key, from, *ch)
+			}
+			continue
+		}
+		if ch == nil {
+			t.Fatalf("key %s moves from %v to %v, but no change covers it", key, from, to)
+		}
+		if !equal(ch.From, from) || !equal(ch.To, to) {
+			t.Fatalf("key %s moves from %v to %v, change %+v says otherwise", key, from, to, *ch)
+		}
+		moved[strings.SplitN(key, "/", 2)[0]]++
+	}
+	// the new eu node takes over keys under the eu policy, which the
+	// unconstrained ring places on it far less often
+	if moved["eu"] == 0 {
+		t.Fatalf("no key under the eu policy moved: %v", moved)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	for _, algo := range []string{AlgorithmConsistent, AlgorithmRendezvous, AlgorithmJump, AlgorithmMaglev} {
+		t.Run(algo, func(t *testing.T) {
+			before, err := NewPlacement(Config{Algorithm: algo, VNodes: 20, Nodes: []string{"a", "b", "c", "d"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			after, err := NewPlacement(Config{Algorithm: algo, VNodes: 20, Nodes: []string{"a", "b", "c", "d", "e"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changes := Diff(before, before, 3); len(changes) != 0 {
+				t.Fatalf("Diff of a placement with itself = %v", changes)
+			}
+
+			changes := Diff(before, after, 3)
+			for i, ch := range changes {
+				if i > 0 && changes[i-1].Range.End >= ch.Range.Start {
+					t.Fatalf("changes overlap or are out of order: %v then %v", changes[i-1].Range, ch.Range)
+				}
+				if len(ch.Added) == 0 || !equal(ch.Added, minus(ch.To, ch.From)) || !equal(ch.Removed, minus(ch.From, ch.To)) {
+					t.Fatalf("inconsistent change %+v", ch)
+				}
+			}
+			// every hash is covered exactly when its replica set changes
+			for i := 0; i < 5000; i++ {
+				h := HashKey(fmt.Sprint(i))
+				var covered *RangeChange
+				for j := range changes {
+					if changes[j].Range.Contains(h) {
+						covered = &changes[j]
+					}
+				}
+				from, to := before.Owners(h, 3), after.Owners(h, 3)
+				switch {
+				case sameSet(from, to) && covered != nil:
+					t.Fatalf("hash %d keeps %v, but %+v moves it", h, from, *covered)
+				case !sameSet(from, to) && covered == nil:
+					t.Fatalf("hash %d moves from %v to %v, but no change covers it", h, from, to)
+				case covered != nil && (!equal(covered.From, from) || !equal(covered.To, to)):
+					t.Fatalf("hash %d moves from %v to %v, change %+v says otherwise", h, from, to, *covered)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package hashring
+
+// ReadReplicas returns the replicas of key to read from, in order of
+// preference: GetReplicaList with the nodes Healthy rejects moved to the
+// end, so a read only falls back to a failed node when nothing else is left.
+func (r *Ring) ReadReplicas(key string, R int) []string {
+	replicas := r.GetReplicaList(key, R)
+	if r.Healthy == nil {
+		return replicas
+	}
+	up := make([]string, 0, len(replicas))
+	var down []string
+	for _, node := range replicas {
+		if r.Healthy(node) {
+			up = append(up, node)
+		} else {
+			down = append(down, node)
+		}
+	}
+	return append(up, down...)
+}
+
+// Preference returns key's preference list: its R replicas followed by
+// every other node allowed to hold it, in placement order. Writes use the
+// nodes after the replicas as substitutes for failed ones, so substitutes
+// obey the key's placement policy and residency rules too.
+func (r *Ring) Preference(key string, R int) []string {
+	replicas := r.GetReplicaList(key, R)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	h := hashKey(key)
+	p := r.policyIndex.lookup(key, h)
+	out := append([]string(nil), replicas...)
+	for _, node := range r.placement.Owners(h, len(r.cfg.Nodes)) {
+		if !contains(out, node) && r.allowed(key, p, node) {
+			out = append(out, node)
+		}
+	}
+	return out
+}
@@ -3,131 +3,211 @@ package hashring
 import (
 	"crypto/sha1"
 	"encoding/json"
-	"fmt"
-	"sort"
-	"strconv"
 	"sync"
 )
 
-// Ring implements a consistent-hash ring with per-key replica overrides.
+// Ring routes keys to nodes through a pluggable Placement, with prefix and
+// hash-range placement policies and per-key replica overrides layered on top.
+// Residency rules override all of them: a key is never routed to a node
+// outside its allowed regions.
 type Ring struct {
 	mu             sync.RWMutex
-	hashes         []uint32            // sorted hashes of virtual nodes
-	nodes          map[uint32]string   // hash -> physical node ID
-	vnodes         int                 // virtual nodes per physical node
-	perKeyReplicas map[string][]string // override replica lists by key
+	cfg            Config                     // current configuration
+	placement      Placement                  // algorithm built from cfg
+	perKeyReplicas map[string][]string        // override replica lists by key
+	policies       map[string]PlacementPolicy // placement policies by name
+	policyIndex    *policyIndex               // built from policies
+	residency      map[string]ResidencyRule   // residency rules by name
+	residencyRules []namedRule                // residency, sorted by name
+
+	// OnViolation, if set, is told about replica overrides that break a
+	// residency rule. Those nodes are left out of the key's replica list.
+	OnViolation func(*ResidencyError)
+	// Healthy, if set, reports whether a node is up. ReadReplicas and
+	// Preference use it to route around failed nodes; placement itself
+	// ignores it.
+	Healthy func(node string) bool
 }
 
-// New creates a Ring with the given number of virtual nodes per physical node.
+// New creates a consistent-hash Ring with the given number of virtual nodes
+// per physical node. /ring/config may later switch the algorithm.
 func New(vnodes int) *Ring {
+	cfg := Config{Algorithm: AlgorithmConsistent, VNodes: vnodes}
 	return &Ring{
-		nodes:          make(map[uint32]string),
-		vnodes:         vnodes,
+		cfg:            cfg,
+		placement:      newConsistent(nil, vnodes, nil),
 		perKeyReplicas: make(map[string][]string),
+		policyIndex:    newPolicyIndex(nil),
 	}
 }
 
-// AddNode adds a physical node to the ring by creating virtual nodes.
+// AddNode adds a physical node to the ring.
 func (r *Ring) AddNode(nodeID string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	for i := 0; i < r.vnodes; i++ {
-		label := nodeID + "#" + strconv.Itoa(i)
-		hash := hashKey(label)
-		r.hashes = append(r.hashes, hash)
-		r.nodes[hash] = nodeID
+	if contains(r.cfg.Nodes, nodeID) {
+		return
 	}
-	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+	cfg := r.cfg
+	cfg.Nodes = append(append([]string(nil), r.cfg.Nodes...), nodeID)
+	r.apply(cfg)
 }
 
-// RemoveNode removes a physical node and its virtual replicas from the ring.
+// RemoveNode removes a physical node from the ring.
 func (r *Ring) RemoveNode(nodeID string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	filtered := r.hashes[:0]
-	for _, h := range r.hashes {
-		if r.nodes[h] == nodeID {
-			delete(r.nodes, h)
-		} else {
-			filtered = append(filtered, h)
+	cfg := r.cfg
+	cfg.Nodes = make([]string, 0, len(r.cfg.Nodes))
+	for _, node := range r.cfg.Nodes {
+		if node != nodeID {
+			cfg.Nodes = append(cfg.Nodes, node)
 		}
 	}
-	r.hashes = filtered
+	r.apply(cfg)
 }
 
 // GetNode returns the primary owner for the given key.
 func (r *Ring) GetNode(key string) string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	h := hashKey(key)
-	idx := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
-	if idx == len(r.hashes) {
-		idx = 0
+	owners := r.placement.Owners(hashKey(key), 1)
+	if len(owners) == 0 {
+		return ""
 	}
-	return r.nodes[r.hashes[idx]]
+	return owners[0]
 }
 
-// GetReplicaList returns either the override replicas for a key or the default R successors.
+// GetReplicaList returns the override replicas for a key if there are any,
+// else the R owners its placement policy allows, else the default R owners.
+// Nodes the key's residency rules forbid are never returned.
 func (r *Ring) GetReplicaList(key string, R int) []string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	if repls, ok := r.perKeyReplicas[key]; ok {
-		return repls
+		if r.checkResidency(key, repls) == nil {
+			return repls
+		}
+		var out []string
+		for _, node := range repls {
+			if r.resident(key, node) {
+				out = append(out, node)
+			}
+		}
+		return out
 	}
-	// default: primary + R-1 successors
-	list := make([]string, 0, R)
 	h := hashKey(key)
-	// find starting index
-	idx := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
-	for i := 0; len(list) < R && i < len(r.hashes); i++ {
-		i2 := (idx + i) % len(r.hashes)
-		node := r.nodes[r.hashes[i2]]
-		// avoid duplicates
-		if !contains(list, node) {
-			list = append(list, node)
-		}
+	p := r.policyIndex.lookup(key, h)
+	if p == nil && !r.hasResidency(key) {
+		return r.placement.Owners(h, R)
 	}
-	return list
+	return r.constrainedOwners(key, p, h, R)
 }
 
 // Update rebuilds the ring configuration from JSON-encoded metadata.
+// Configurations older than the current epoch are ignored; unversioned ones,
+// written without the metadata store, always apply.
 func (r *Ring) Update(raw []byte) {
-	var cfg struct {
-		VNodes int      `json:"vnodes_per_node"`
-		Nodes  []string `json:"nodes"`
-	}
+	var cfg Config
 	if err := json.Unmarshal(raw, &cfg); err != nil {
 		return
 	}
 
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if cfg.Epoch != 0 && cfg.Epoch < r.cfg.Epoch {
+		return
+	}
+	if cfg.VNodes == 0 {
+		cfg.VNodes = r.cfg.VNodes
+	}
+	r.apply(cfg)
+}
+
+// Config returns a copy of the ring's current configuration.
+func (r *Ring) Config() Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cfg := r.cfg
+	cfg.Nodes = append([]string(nil), r.cfg.Nodes...)
+	return cfg
+}
+
+// Epoch returns the epoch of the configuration in use, 0 if it was never
+// published through the metadata store.
+func (r *Ring) Epoch() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cfg.Epoch
+}
 
-	r.vnodes = cfg.VNodes
-	r.hashes = r.hashes[:0]
-	// clear nodes map
-	r.nodes = make(map[uint32]string)
-	// re-add all physical nodes
-	for _, node := range cfg.Nodes {
-		for i := 0; i < r.vnodes; i++ {
-			hash := hashKey(fmt.Sprintf("%s#%d", node, i))
-			r.hashes = append(r.hashes, hash)
-			r.nodes[hash] = node
+// Region returns the region a node runs in, or "" if it is not configured.
+func (r *Ring) Region(node string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cfg.Regions[node]
+}
+
+// HasRegion reports whether some node of the configuration runs in region.
+func (r *Ring) HasRegion(region string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, rg := range r.cfg.Regions {
+		if rg == region {
+			return true
 		}
 	}
-	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+	return false
+}
+
+// Placement returns the placement currently used for routing. Placements
+// are immutable, so the result stays valid after later updates.
+func (r *Ring) Placement() Placement {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.placement
+}
+
+// apply swaps in the placement for cfg. Invalid configurations are ignored
+// so a bad /ring/config write cannot take routing down. Caller holds r.mu.
+func (r *Ring) apply(cfg Config) {
+	p, err := NewPlacement(cfg)
+	if err != nil {
+		return
+	}
+	r.cfg = cfg
+	r.placement = p
 }
 
-// UpdateReplicas updates the per-key replica list for a specific key.
+// UpdateReplicas updates the per-key replica list for a specific key. Empty
+// raw removes the override, returning the key to its default placement.
 func (r *Ring) UpdateReplicas(key string, raw []byte) {
+	if len(raw) == 0 {
+		r.mu.Lock()
+		delete(r.perKeyReplicas, key)
+		r.mu.Unlock()
+		return
+	}
 	var repls []string
 	if err := json.Unmarshal(raw, &repls); err != nil {
 		return
 	}
 
 	r.mu.Lock()
-	defer r.mu.Unlock()
 	r.perKeyReplicas[key] = repls
+	err := r.checkResidency(key, repls)
+	r.mu.Unlock()
+	if err != nil {
+		r.report(err)
+	}
+}
+
+// Override returns the per-key replica override for key, if any.
+func (r *Ring) Override(key string) ([]string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	repls, ok := r.perKeyReplicas[key]
+	return repls, ok
 }
 
 // helper: check if slice contains a string
@@ -140,6 +220,12 @@ func contains(slice []string, s string) bool {
 	return false
 }
 
+// HashKey returns the ring hash of a key, as used by Placement.Owners and
+// Range.
+func HashKey(key string) uint32 {
+	return hashKey(key)
+}
+
 // hashKey hashes a string to a uint32 using SHA-1.
 func hashKey(key string) uint32 {
 	sum := sha1.Sum([]byte(key))
@@ -151,14 +237,5 @@ func hashKey(key string) uint32 {
 func (r *Ring) AllNodes() []string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-
-	seen := make(map[string]struct{}, len(r.nodes))
-	for _, node := range r.nodes {
-		seen[node] = struct{}{}
-	}
-	result := make([]string, 0, len(seen))
-	for node := range seen {
-		result = append(result, node)
-	}
-	return result
+	return append([]string(nil), r.cfg.Nodes...)
 }
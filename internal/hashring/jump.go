@@ -0,0 +1,50 @@
+package hashring
+
+// jump implements Lamping & Veach's jump consistent hash. Buckets are the
+// nodes in configuration order, so nodes should only be appended: removing a
+// node from the middle of the list renumbers every bucket after it.
+type jump struct {
+	nodes []string
+}
+
+func newJump(nodes []string) *jump {
+	return &jump{nodes: append([]string(nil), nodes...)}
+}
+
+func (j *jump) Algorithm() string { return AlgorithmJump }
+
+func (j *jump) Boundaries() []uint32 { return partitionBoundaries() }
+
+// Owners picks the primary with jump hash and derives further replicas by
+// re-hashing the partition with a per-attempt salt.
+func (j *jump) Owners(h uint32, n int) []string {
+	if n > len(j.nodes) {
+		n = len(j.nodes)
+	}
+	list := make([]string, 0, n)
+	key := mix64(uint64(partition(h)))
+	for attempt := uint64(0); len(list) < n && attempt < uint64(4*len(j.nodes)); attempt++ {
+		node := j.nodes[jumpHash(mix64(key+attempt), len(j.nodes))]
+		if !contains(list, node) {
+			list = append(list, node)
+		}
+	}
+	// fall back to configuration order if salting kept colliding
+	for i := 0; len(list) < n; i++ {
+		if !contains(list, j.nodes[i]) {
+			list = append(list, j.nodes[i])
+		}
+	}
+	return list
+}
+
+// jumpHash maps key to a bucket in [0, buckets).
+func jumpHash(key uint64, buckets int) int {
+	var b, next int64 = -1, 0
+	for next < int64(buckets) {
+		b = next
+		key = key*2862933555777941757 + 1
+		next = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
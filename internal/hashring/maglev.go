@@ -0,0 +1,113 @@
+package hashring
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DefaultMaglevTableSize is the lookup table size used when /ring/config does
+// not set one. It must be prime; 65537 gives every partition its own entry.
+const DefaultMaglevTableSize = 65537
+
+// maglev implements Google's Maglev lookup table. Nodes claim entries in
+// proportion to their weights.
+type maglev struct {
+	nodes   []string
+	weights []float64 // by index into nodes
+	table   []int     // entry -> index into nodes, -1 when there are no nodes
+}
+
+func newMaglev(nodes []string, size int, weights map[string]float64) (*maglev, error) {
+	if size == 0 {
+		size = DefaultMaglevTableSize
+	}
+	if !isPrime(size) {
+		return nil, fmt.Errorf("maglev table size %d is not prime", size)
+	}
+	// sort so the table does not depend on configuration order
+	m := &maglev{nodes: append([]string(nil), nodes...), table: make([]int, size)}
+	sort.Strings(m.nodes)
+	m.weights = make([]float64, len(m.nodes))
+	for i, node := range m.nodes {
+		m.weights[i] = weight(weights, node)
+	}
+	m.populate()
+	return m, nil
+}
+
+// populate fills the table by letting every node claim entries in turn
+// following its own permutation. Each turn adds a node's weight relative
+// to the heaviest to its credit, and it claims an entry per whole credit.
+func (m *maglev) populate() {
+	size := uint64(len(m.table))
+	for i := range m.table {
+		m.table[i] = -1
+	}
+	if len(m.nodes) == 0 {
+		return
+	}
+	offsets := make([]uint64, len(m.nodes))
+	skips := make([]uint64, len(m.nodes))
+	next := make([]uint64, len(m.nodes))
+	for i, node := range m.nodes {
+		seed := nodeSeed(node)
+		offsets[i] = seed % size
+		skips[i] = mix64(seed)%(size-1) + 1
+	}
+	maxWeight := 0.0
+	for _, w := range m.weights {
+		maxWeight = math.Max(maxWeight, w)
+	}
+	credit := make([]float64, len(m.nodes))
+	for filled := uint64(0); ; {
+		for i := range m.nodes {
+			for credit[i] += m.weights[i] / maxWeight; credit[i] >= 1; credit[i]-- {
+				c := (offsets[i] + next[i]*skips[i]) % size
+				for m.table[c] >= 0 {
+					next[i]++
+					c = (offsets[i] + next[i]*skips[i]) % size
+				}
+				m.table[c] = i
+				next[i]++
+				filled++
+				if filled == size {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (m *maglev) Algorithm() string { return AlgorithmMaglev }
+
+func (m *maglev) Boundaries() []uint32 { return partitionBoundaries() }
+
+// Owners takes the partition's table entry as primary and walks the table
+// forward for further distinct nodes.
+func (m *maglev) Owners(h uint32, n int) []string {
+	if n > len(m.nodes) {
+		n = len(m.nodes)
+	}
+	list := make([]string, 0, n)
+	idx := int(partition(h)) % len(m.table)
+	for i := 0; len(list) < n && i < len(m.table); i++ {
+		node := m.nodes[m.table[(idx+i)%len(m.table)]]
+		if !contains(list, node) {
+			list = append(list, node)
+		}
+	}
+	return list
+}
+
+func isPrime(n int) bool {
+	if n < 2 {
+		return false
+	}
+	for d := 2; d*d <= n; d++ {
+		if n%d == 0 {
+			return false
+		}
+	}
+	return true
+}
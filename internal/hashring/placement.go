@@ -0,0 +1,117 @@
+package hashring
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+)
+
+// Supported placement algorithms, selected by the "algorithm" field of /ring/config.
+const (
+	AlgorithmConsistent = "consistent"
+	AlgorithmRendezvous = "rendezvous"
+	AlgorithmJump       = "jump"
+	AlgorithmMaglev     = "maglev"
+)
+
+// Algorithms lists every supported placement algorithm.
+var Algorithms = []string{AlgorithmConsistent, AlgorithmRendezvous, AlgorithmJump, AlgorithmMaglev}
+
+// partitionBits is the number of high hash bits that select a partition for
+// the partitioned algorithms (rendezvous, jump, maglev). Every key whose hash
+// falls into the same partition has the same owners.
+const partitionBits = 16
+
+// Placement maps a key hash to an ordered list of owning nodes.
+type Placement interface {
+	// Algorithm returns the name of the algorithm as used in /ring/config.
+	Algorithm() string
+	// Owners returns up to n distinct nodes for the hash, primary first.
+	Owners(h uint32, n int) []string
+	// Boundaries returns, in ascending order and starting with 0, the hashes
+	// at which ownership may change. Owners is constant between two
+	// consecutive boundaries.
+	Boundaries() []uint32
+}
+
+// Config is the JSON document stored under /ring/config.
+type Config struct {
+	Algorithm       string   `json:"algorithm,omitempty"`
+	VNodes          int      `json:"vnodes_per_node"`
+	Nodes           []string `json:"nodes"`
+	MaglevTableSize int      `json:"maglev_table_size,omitempty"`
+	// Regions maps node IDs to the region they run in.
+	Regions map[string]string `json:"regions,omitempty"`
+	// Weights are the nodes' relative capacities, 1 if missing: a node
+	// with weight 2 owns about twice the keys of one with weight 1. The
+	// jump algorithm does not support them.
+	Weights map[string]float64 `json:"weights,omitempty"`
+	// Epoch numbers published configurations; the metadata store assigns
+	// it, one past the configuration replaced.
+	Epoch uint64 `json:"epoch,omitempty"`
+}
+
+// NewPlacement builds the placement described by cfg. An empty algorithm
+// selects consistent hashing.
+func NewPlacement(cfg Config) (Placement, error) {
+	for node, w := range cfg.Weights {
+		if !(w > 0) {
+			return nil, fmt.Errorf("weight of %s must be positive, got %v", node, w)
+		}
+	}
+	switch cfg.Algorithm {
+	case "", AlgorithmConsistent:
+		return newConsistent(cfg.Nodes, cfg.VNodes, cfg.Weights), nil
+	case AlgorithmRendezvous:
+		return newRendezvous(cfg.Nodes, cfg.Weights), nil
+	case AlgorithmJump:
+		for _, w := range cfg.Weights {
+			if w != 1 {
+				return nil, fmt.Errorf("the %s algorithm does not support node weights", AlgorithmJump)
+			}
+		}
+		return newJump(cfg.Nodes), nil
+	case AlgorithmMaglev:
+		return newMaglev(cfg.Nodes, cfg.MaglevTableSize, cfg.Weights)
+	default:
+		return nil, fmt.Errorf("unknown placement algorithm %q", cfg.Algorithm)
+	}
+}
+
+// weight returns node's weight in weights, 1 if it has none.
+func weight(weights map[string]float64, node string) float64 {
+	if w, ok := weights[node]; ok {
+		return w
+	}
+	return 1
+}
+
+// partition returns the partition a hash belongs to.
+func partition(h uint32) uint32 {
+	return h >> (32 - partitionBits)
+}
+
+// partitionBoundaries returns the first hash of every partition.
+func partitionBoundaries() []uint32 {
+	b := make([]uint32, 1<<partitionBits)
+	for i := range b {
+		b[i] = uint32(i) << (32 - partitionBits)
+	}
+	return b
+}
+
+// nodeSeed derives a stable 64-bit seed for a node ID.
+func nodeSeed(node string) uint64 {
+	sum := sha1.Sum([]byte(node))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// mix64 is the splitmix64 finalizer, used to spread partition numbers.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,84 @@
+// internal/hashring/placement_test.go
+package hashring
+
+import (
+	"strconv"
+	"testing"
+)
+
+const (
+	benchNodes = 10
+	benchKeys  = 1 << 16
+	benchR     = 3
+)
+
+func benchConfig(algo string) Config {
+	cfg := Config{Algorithm: algo, VNodes: 100}
+	for i := 0; i < benchNodes; i++ {
+		cfg.Nodes = append(cfg.Nodes, "node-"+strconv.Itoa(i)+":50051")
+	}
+	return cfg
+}
+
+func benchHashes() []uint32 {
+	hashes := make([]uint32, benchKeys)
+	for i := range hashes {
+		hashes[i] = hashKey("key-" + strconv.Itoa(i))
+	}
+	return hashes
+}
+
+// benchmarkPlacement measures building the placement and looking up the
+// owners of a key. The lookup benchmark also reports the share of keys whose
+// replica set changes when a node joins (ideal: about benchR/benchNodes).
+func benchmarkPlacement(b *testing.B, algo string) {
+	cfg := benchConfig(algo)
+	b.Run("build", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := NewPlacement(cfg); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("lookup", func(b *testing.B) {
+		p, err := NewPlacement(cfg)
+		if err != nil {
+			b.Fatal(err)
+		}
+		hashes := benchHashes()
+		moved := movedOnAdd(b, cfg, hashes)
+		i := 0
+		for b.Loop() {
+			p.Owners(hashes[i%len(hashes)], benchR)
+			i++
+		}
+		b.ReportMetric(moved, "moved/key")
+	})
+}
+
+func BenchmarkPlacement_Consistent(b *testing.B) { benchmarkPlacement(b, AlgorithmConsistent) }
+func BenchmarkPlacement_Rendezvous(b *testing.B) { benchmarkPlacement(b, AlgorithmRendezvous) }
+func BenchmarkPlacement_Jump(b *testing.B)       { benchmarkPlacement(b, AlgorithmJump) }
+func BenchmarkPlacement_Maglev(b *testing.B)     { benchmarkPlacement(b, AlgorithmMaglev) }
+
+// movedOnAdd returns the fraction of hashes whose replica set changes when a
+// node is added to cfg.
+func movedOnAdd(b *testing.B, cfg Config, hashes []uint32) float64 {
+	added := cfg
+	added.Nodes = append(append([]string(nil), cfg.Nodes...), "added-node:50051")
+	before, err := NewPlacement(cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	after, err := NewPlacement(added)
+	if err != nil {
+		b.Fatal(err)
+	}
+	moved := 0
+	for _, h := range hashes {
+		if !sameSet(before.Owners(h, benchR), after.Owners(h, benchR)) {
+			moved++
+		}
+	}
+	return float64(moved) / float64(len(hashes))
+}
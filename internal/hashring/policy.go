@@ -0,0 +1,221 @@
+package hashring
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// PlacementPolicy constrains the replicas of every key matching a key prefix
+// or a hash range. It is the JSON document stored under /policies/<name>,
+// e.g. {"prefix": "user/eu/", "regions": ["eu-west"]}.
+type PlacementPolicy struct {
+	// Exactly one of Prefix and Range selects the keys.
+	Prefix string `json:"prefix,omitempty"`
+	Range  *Range `json:"range,omitempty"`
+	// Nodes and Regions restrict the replicas to the listed nodes and the
+	// nodes of the listed regions. Owners keep their placement order, so
+	// keys still spread over the allowed nodes.
+	Nodes   []string `json:"nodes,omitempty"`
+	Regions []string `json:"regions,omitempty"`
+	// Replicas overrides the replication factor if positive.
+	Replicas int `json:"replicas,omitempty"`
+}
+
+// validate checks that the policy selects keys one way and restricts them
+// to something.
+func (p PlacementPolicy) validate() error {
+	if (p.Prefix == "") == (p.Range == nil) {
+		return fmt.Errorf("policy needs exactly one of prefix and range")
+	}
+	if p.Range != nil && p.Range.Start > p.Range.End {
+		return fmt.Errorf("policy range %d-%d is empty", p.Range.Start, p.Range.End)
+	}
+	if len(p.Nodes) == 0 && len(p.Regions) == 0 {
+		return fmt.Errorf("policy allows no nodes")
+	}
+	return nil
+}
+
+// allows reports whether node may hold keys under the policy.
+func (p *PlacementPolicy) allows(node string, regions map[string]string) bool {
+	return contains(p.Nodes, node) || contains(p.Regions, regions[node])
+}
+
+// namedPolicy is a policy with the etcd name it was stored under.
+type namedPolicy struct {
+	name string
+	PlacementPolicy
+}
+
+// policyIndex resolves the policy of a key: the longest matching prefix
+// through a byte trie, then the hash range containing the key's hash by
+// binary search. It is immutable once built.
+type policyIndex struct {
+	root   trieNode
+	ranges []*namedPolicy // non-overlapping, by Range.Start
+}
+
+type trieNode struct {
+	children map[byte]*trieNode
+	policy   *namedPolicy
+}
+
+// newPolicyIndex indexes policies. A range policy overlapping one whose
+// name sorts earlier is left out, so lookups stay unambiguous.
+func newPolicyIndex(policies map[string]PlacementPolicy) *policyIndex {
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	idx := &policyIndex{}
+	for _, name := range names {
+		p := &namedPolicy{name: name, PlacementPolicy: policies[name]}
+		if p.Prefix != "" {
+			idx.insert(p)
+			continue
+		}
+		if idx.rangeAt(p.Range.Start) == nil && idx.rangeAt(p.Range.End) == nil && !idx.covers(*p.Range) {
+			idx.ranges = append(idx.ranges, p)
+			sort.Slice(idx.ranges, func(i, j int) bool { return idx.ranges[i].Range.Start < idx.ranges[j].Range.Start })
+		}
+	}
+	return idx
+}
+
+func (idx *policyIndex) insert(p *namedPolicy) {
+	n := &idx.root
+	for i := 0; i < len(p.Prefix); i++ {
+		if n.children == nil {
+			n.children = make(map[byte]*trieNode)
+		}
+		child, ok := n.children[p.Prefix[i]]
+		if !ok {
+			child = &trieNode{}
+			n.children[p.Prefix[i]] = child
+		}
+		n = child
+	}
+	n.policy = p
+}
+
+// lookup returns the policy for key, preferring prefixes over ranges.
+func (idx *policyIndex) lookup(key string, h uint32) *namedPolicy {
+	if p := idx.prefixAt(key); p != nil {
+		return p
+	}
+	return idx.rangeAt(h)
+}
+
+// prefixAt returns the prefix policy with the longest prefix of key, if any.
+func (idx *policyIndex) prefixAt(key string) *namedPolicy {
+	var best *namedPolicy
+	n := &idx.root
+	for i := 0; i < len(key) && n != nil; i++ {
+		n = n.children[key[i]]
+		if n != nil && n.policy != nil {
+			best = n.policy
+		}
+	}
+	return best
+}
+
+// rangeAt returns the range policy containing h, if any.
+func (idx *policyIndex) rangeAt(h uint32) *namedPolicy {
+	i := sort.Search(len(idx.ranges), func(i int) bool { return idx.ranges[i].Range.Start > h })
+	if i > 0 && idx.ranges[i-1].Range.Contains(h) {
+		return idx.ranges[i-1]
+	}
+	return nil
+}
+
+// covers reports whether an indexed range lies inside r.
+func (idx *policyIndex) covers(r Range) bool {
+	for _, p := range idx.ranges {
+		if r.Contains(p.Range.Start) {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdatePolicy sets the placement policy stored under name from its JSON
+// encoding; empty raw removes it. Invalid policies are ignored.
+func (r *Ring) UpdatePolicy(name string, raw []byte) {
+	var p PlacementPolicy
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &p); err != nil || p.validate() != nil {
+			return
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	policies := make(map[string]PlacementPolicy, len(r.policies)+1)
+	for n, old := range r.policies {
+		policies[n] = old
+	}
+	if len(raw) == 0 {
+		delete(policies, name)
+	} else {
+		policies[name] = p
+	}
+	r.policies = policies
+	r.policyIndex = newPolicyIndex(policies)
+}
+
+// Policy returns the name and contents of the placement policy that
+// governs key, if any.
+func (r *Ring) Policy(key string) (string, PlacementPolicy, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p := r.policyIndex.lookup(key, hashKey(key))
+	if p == nil {
+		return "", PlacementPolicy{}, false
+	}
+	return p.name, p.PlacementPolicy, true
+}
+
+// Candidates returns the nodes allowed to hold key: those its placement
+// policy and residency rules allow.
+func (r *Ring) Candidates(key string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p := r.policyIndex.lookup(key, hashKey(key))
+	var out []string
+	for _, node := range r.cfg.Nodes {
+		if r.allowed(key, p, node) {
+			out = append(out, node)
+		}
+	}
+	return out
+}
+
+// constrainedOwners returns up to R owners of h among the nodes allowed to
+// hold key, in placement order; p may be nil. Caller holds r.mu.
+func (r *Ring) constrainedOwners(key string, p *namedPolicy, h uint32, R int) []string {
+	if p != nil && p.Replicas > 0 {
+		R = p.Replicas
+	}
+	var out []string
+	for _, node := range r.placement.Owners(h, len(r.cfg.Nodes)) {
+		if len(out) == R {
+			break
+		}
+		if r.allowed(key, p, node) {
+			out = append(out, node)
+		}
+	}
+	return out
+}
+
+// allowed reports whether the policy p, which may be nil, and the residency
+// rules let node hold key. Caller holds r.mu.
+func (r *Ring) allowed(key string, p *namedPolicy, node string) bool {
+	if p != nil && !p.allows(node, r.cfg.Regions) {
+		return false
+	}
+	return r.resident(key, node)
+}
@@ -0,0 +1,44 @@
+// internal/hashring/policy_test.go
+package hashring
+
+import "testing"
+
+func TestPolicyIndex(t *testing.T) {
+	idx := newPolicyIndex(map[string]PlacementPolicy{
+		"eu":     {Prefix: "eu/", Regions: []string{"eu"}},
+		"eu-pii": {Prefix: "eu/pii/", Regions: []string{"eu-central"}},
+		"low":    {Range: &Range{Start: 0, End: 99}, Nodes: []string{"a"}},
+		"mid":    {Range: &Range{Start: 200, End: 299}, Nodes: []string{"b"}},
+		// overlaps "low", whose name sorts first, so it is left out
+		"overlap": {Range: &Range{Start: 50, End: 150}, Nodes: []string{"c"}},
+		// contains "mid" without covering either of its ends
+		"wrap": {Range: &Range{Start: 150, End: 400}, Nodes: []string{"c"}},
+	})
+
+	for _, tc := range []struct {
+		key  string
+		h    uint32
+		want string
+	}{
+		{"eu/1", 1000, "eu"},
+		{"eu/pii/1", 1000, "eu-pii"}, // the longest prefix wins
+		{"eu/pi", 1000, "eu"},
+		{"eu/pii/1", 10, "eu-pii"}, // prefixes win over ranges
+		{"us/1", 0, "low"},
+		{"us/1", 99, "low"},
+		{"us/1", 100, ""},
+		{"us/1", 120, ""}, // only the overlapping policy covered it
+		{"us/1", 250, "mid"},
+		{"us/1", 299, "mid"},
+		{"us/1", 350, ""},
+		{"e", 10, "low"}, // shorter than every prefix
+	} {
+		got := ""
+		if p := idx.lookup(tc.key, tc.h); p != nil {
+			got = p.name
+		}
+		if got != tc.want {
+			t.Errorf("lookup(%q, %d) = %q, want %q", tc.key, tc.h, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package hashring
+
+import (
+	"math"
+	"sort"
+)
+
+// rendezvous implements highest-random-weight hashing: every node scores the
+// key's partition and the highest scores win. Weighted nodes score
+// w / -ln(u) for their uniform score u in (0, 1), which gives each node a
+// share of partitions proportional to its weight.
+type rendezvous struct {
+	nodes   []string
+	seeds   []uint64
+	weights []float64 // nil when every node weighs 1
+}
+
+func newRendezvous(nodes []string, weights map[string]float64) *rendezvous {
+	r := &rendezvous{nodes: append([]string(nil), nodes...)}
+	r.seeds = make([]uint64, len(nodes))
+	for i, node := range nodes {
+		r.seeds[i] = nodeSeed(node)
+	}
+	for _, node := range nodes {
+		if weight(weights, node) != 1 {
+			r.weights = make([]float64, len(nodes))
+			for i, node := range nodes {
+				r.weights[i] = weight(weights, node)
+			}
+			break
+		}
+	}
+	return r
+}
+
+// weighted maps a raw score to its weighted score for node i, preserving
+// the order of raw scores between nodes of equal weight.
+func (r *rendezvous) weighted(i int, score uint64) float64 {
+	u := (float64(score>>11) + 0.5) / (1 << 53)
+	return r.weights[i] / -math.Log(u)
+}
+
+func (r *rendezvous) Algorithm() string { return AlgorithmRendezvous }
+
+func (r *rendezvous) Boundaries() []uint32 { return partitionBoundaries() }
+
+func (r *rendezvous) Owners(h uint32, n int) []string {
+	if n > len(r.nodes) {
+		n = len(r.nodes)
+	}
+	if n <= 0 {
+		return []string{}
+	}
+	p := uint64(partition(h))
+	type scored struct {
+		node     string
+		score    uint64
+		weighted float64
+	}
+	all := make([]scored, len(r.nodes))
+	for i, node := range r.nodes {
+		all[i] = scored{node: node, score: mix64(r.seeds[i] ^ mix64(p))}
+		if r.weights != nil {
+			all[i].weighted = r.weighted(i, all[i].score)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].weighted != all[j].weighted {
+			return all[i].weighted > all[j].weighted
+		}
+		if all[i].score != all[j].score {
+			return all[i].score > all[j].score
+		}
+		return all[i].node < all[j].node
+	})
+	list := make([]string, n)
+	for i := range list {
+		list[i] = all[i].node
+	}
+	return list
+}
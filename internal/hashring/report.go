@@ -0,0 +1,152 @@
+package hashring
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// Movement summarizes how many keys change owners between two placements.
+type Movement struct {
+	Keys            int     `json:"keys"`
+	PrimaryMoved    int     `json:"primary_moved"`
+	ReplicaSetMoved int     `json:"replica_set_moved"`
+	PrimaryFraction float64 `json:"primary_fraction"`
+	ReplicaFraction float64 `json:"replica_fraction"`
+}
+
+// Measure compares the owners of each key under before and after, using
+// replication factor R for the replica-set comparison.
+func Measure(before, after Placement, keys []string, R int) Movement {
+	m := Movement{Keys: len(keys)}
+	for _, key := range keys {
+		h := hashKey(key)
+		b, a := before.Owners(h, R), after.Owners(h, R)
+		if len(b) > 0 && len(a) > 0 && b[0] != a[0] {
+			m.PrimaryMoved++
+		}
+		if !sameSet(b, a) {
+			m.ReplicaSetMoved++
+		}
+	}
+	if len(keys) > 0 {
+		m.PrimaryFraction = float64(m.PrimaryMoved) / float64(len(keys))
+		m.ReplicaFraction = float64(m.ReplicaSetMoved) / float64(len(keys))
+	}
+	return m
+}
+
+// Shares returns each node's share of the hash space as primary, which is
+// its expected share of keys.
+func Shares(p Placement) map[string]float64 {
+	shares := make(map[string]float64)
+	b := p.Boundaries()
+	for i, start := range b {
+		end := uint64(math.MaxUint32) + 1
+		if i+1 < len(b) {
+			end = uint64(b[i+1])
+		}
+		if owners := p.Owners(start, 1); len(owners) > 0 {
+			shares[owners[0]] += float64(end-uint64(start)) / (1 << 32)
+		}
+	}
+	return shares
+}
+
+// AlgorithmReport is one row of Compare's output.
+type AlgorithmReport struct {
+	Algorithm string `json:"algorithm"`
+	// LookupNs is the mean cost of an R-replica lookup.
+	LookupNs float64 `json:"lookup_ns"`
+	// BuildNs is the cost of building the placement for the base node set.
+	BuildNs int64 `json:"build_ns"`
+	// MaxLoad is the busiest node's share of primaries relative to a perfect
+	// split (1.0 is perfectly balanced).
+	MaxLoad    float64  `json:"max_load"`
+	LoadStdDev float64  `json:"load_stddev"`
+	OnAdd      Movement `json:"on_add"`
+	OnRemove   Movement `json:"on_remove"`
+}
+
+// Compare builds every algorithm over nodes and reports lookup cost, load
+// balance and key movement when a node is appended and when the first node
+// is removed. The ideal movement for both is about 1/len(nodes) of the keys.
+func Compare(cfg Config, keys []string, R int) ([]AlgorithmReport, error) {
+	added := cfg
+	added.Nodes = append(append([]string(nil), cfg.Nodes...), "added-node-"+strconv.Itoa(len(cfg.Nodes)))
+	removed := cfg
+	if len(cfg.Nodes) > 0 {
+		removed.Nodes = cfg.Nodes[1:]
+	}
+
+	reports := make([]AlgorithmReport, 0, len(Algorithms))
+	for _, algo := range Algorithms {
+		cfg.Algorithm, added.Algorithm, removed.Algorithm = algo, algo, algo
+
+		start := time.Now()
+		base, err := NewPlacement(cfg)
+		if err != nil {
+			return nil, err
+		}
+		rep := AlgorithmReport{Algorithm: algo, BuildNs: time.Since(start).Nanoseconds()}
+
+		load := make(map[string]int, len(cfg.Nodes))
+		start = time.Now()
+		for _, key := range keys {
+			if owners := base.Owners(hashKey(key), R); len(owners) > 0 {
+				load[owners[0]]++
+			}
+		}
+		if len(keys) > 0 {
+			rep.LookupNs = float64(time.Since(start).Nanoseconds()) / float64(len(keys))
+		}
+		rep.MaxLoad, rep.LoadStdDev = balance(load, len(cfg.Nodes), len(keys))
+
+		withAdded, err := NewPlacement(added)
+		if err != nil {
+			return nil, err
+		}
+		withRemoved, err := NewPlacement(removed)
+		if err != nil {
+			return nil, err
+		}
+		rep.OnAdd = Measure(base, withAdded, keys, R)
+		rep.OnRemove = Measure(base, withRemoved, keys, R)
+		reports = append(reports, rep)
+	}
+	return reports, nil
+}
+
+// balance returns the max load relative to the mean and the relative stddev.
+func balance(load map[string]int, nodes, keys int) (float64, float64) {
+	if nodes == 0 || keys == 0 {
+		return 0, 0
+	}
+	mean := float64(keys) / float64(nodes)
+	var max, sq float64
+	seen := 0
+	for _, n := range load {
+		f := float64(n)
+		if f > max {
+			max = f
+		}
+		sq += (f - mean) * (f - mean)
+		seen++
+	}
+	// nodes that own nothing still count towards the deviation
+	sq += float64(nodes-seen) * mean * mean
+	return max / mean, math.Sqrt(sq/float64(nodes)) / mean
+}
+
+// sameSet reports whether a and b hold the same nodes, ignoring order.
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, v := range a {
+		if !contains(b, v) {
+			return false
+		}
+	}
+	return true
+}
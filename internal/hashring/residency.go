@@ -0,0 +1,169 @@
+package hashring
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrResidency is matched by every *ResidencyError.
+var ErrResidency = errors.New("data residency violation")
+
+// ResidencyRule keeps every key with Prefix inside Regions. It is the JSON
+// document stored under /residency/<name>, e.g.
+// {"prefix": "user/eu/", "regions": ["eu-west", "eu-central"]}.
+type ResidencyRule struct {
+	Prefix  string   `json:"prefix"`
+	Regions []string `json:"regions"`
+}
+
+func (rule ResidencyRule) validate() error {
+	if rule.Prefix == "" {
+		return fmt.Errorf("residency rule needs a prefix")
+	}
+	if len(rule.Regions) == 0 {
+		return fmt.Errorf("residency rule allows no regions")
+	}
+	return nil
+}
+
+// namedRule is a residency rule with the etcd name it was stored under.
+type namedRule struct {
+	name string
+	ResidencyRule
+}
+
+// ResidencyError reports a node that may not hold a key.
+type ResidencyError struct {
+	Key     string   `json:"key"`
+	Node    string   `json:"node"`
+	Region  string   `json:"region"`
+	Rule    string   `json:"rule"`
+	Allowed []string `json:"allowed_regions"`
+}
+
+func (e *ResidencyError) Error() string {
+	return fmt.Sprintf("key %q may not be stored on %s in region %q: residency rule %s allows %v",
+		e.Key, e.Node, e.Region, e.Rule, e.Allowed)
+}
+
+// Is makes errors.Is(err, ErrResidency) hold.
+func (e *ResidencyError) Is(target error) bool {
+	return target == ErrResidency
+}
+
+// UpdateResidency sets the residency rule stored under name from its JSON
+// encoding; empty raw removes it. Invalid rules are ignored. Existing
+// replica overrides the rule forbids are reported to OnViolation.
+func (r *Ring) UpdateResidency(name string, raw []byte) {
+	var rule ResidencyRule
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &rule); err != nil || rule.validate() != nil {
+			return
+		}
+	}
+
+	r.mu.Lock()
+	rules := make(map[string]ResidencyRule, len(r.residency)+1)
+	for n, old := range r.residency {
+		rules[n] = old
+	}
+	if len(raw) == 0 {
+		delete(rules, name)
+	} else {
+		rules[name] = rule
+	}
+	r.residency = rules
+	r.residencyRules = make([]namedRule, 0, len(rules))
+	for n, rule := range rules {
+		r.residencyRules = append(r.residencyRules, namedRule{name: n, ResidencyRule: rule})
+	}
+	sort.Slice(r.residencyRules, func(i, j int) bool { return r.residencyRules[i].name < r.residencyRules[j].name })
+	var violations []*ResidencyError
+	if len(raw) > 0 {
+		for key, repls := range r.perKeyReplicas {
+			if strings.HasPrefix(key, rule.Prefix) {
+				if err := r.checkResidency(key, repls); err != nil {
+					violations = append(violations, err)
+				}
+			}
+		}
+	}
+	r.mu.Unlock()
+	r.report(violations...)
+}
+
+// CheckResidency returns a *ResidencyError for the first of nodes that may
+// not hold key, or nil if all of them may.
+func (r *Ring) CheckResidency(key string, nodes []string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if err := r.checkResidency(key, nodes); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ResidencyExclusions returns the key prefixes that may not be stored in
+// region.
+func (r *Ring) ResidencyExclusions(region string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []string
+	for _, rule := range r.residency {
+		if !contains(rule.Regions, region) {
+			out = append(out, rule.Prefix)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// checkResidency is CheckResidency. Every rule matching key applies. Caller
+// holds r.mu.
+func (r *Ring) checkResidency(key string, nodes []string) *ResidencyError {
+	for _, rule := range r.residencyRules {
+		if !strings.HasPrefix(key, rule.Prefix) {
+			continue
+		}
+		for _, node := range nodes {
+			if region := r.cfg.Regions[node]; !contains(rule.Regions, region) {
+				return &ResidencyError{Key: key, Node: node, Region: region, Rule: rule.name, Allowed: rule.Regions}
+			}
+		}
+	}
+	return nil
+}
+
+// hasResidency reports whether any residency rule covers key. Caller holds r.mu.
+func (r *Ring) hasResidency(key string) bool {
+	for _, rule := range r.residencyRules {
+		if strings.HasPrefix(key, rule.Prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// resident reports whether node may hold key. Caller holds r.mu.
+func (r *Ring) resident(key, node string) bool {
+	region := r.cfg.Regions[node]
+	for _, rule := range r.residencyRules {
+		if strings.HasPrefix(key, rule.Prefix) && !contains(rule.Regions, region) {
+			return false
+		}
+	}
+	return true
+}
+
+// report passes violations to OnViolation, if set. Caller must not hold r.mu.
+func (r *Ring) report(violations ...*ResidencyError) {
+	if r.OnViolation == nil {
+		return
+	}
+	for _, v := range violations {
+		r.OnViolation(v)
+	}
+}
@@ -0,0 +1,39 @@
+// internal/hashring/residency_test.go
+package hashring
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckResidency(t *testing.T) {
+	r := New(20)
+	r.Update(testConfig(t, "eu-a", "us-a"))
+	r.UpdateResidency("b-pii", []byte(`{"prefix": "eu/pii/", "regions": ["eu"]}`))
+	r.UpdateResidency("a-eu", []byte(`{"prefix": "eu/", "regions": ["eu", "ch"]}`))
+
+	if err := r.CheckResidency("eu/pii/1", []string{"eu-a"}); err != nil {
+		t.Fatalf("eu node rejected: %v", err)
+	}
+	err := r.CheckResidency("eu/pii/1", []string{"eu-a", "us-a"})
+	var rerr *ResidencyError
+	if !errors.As(err, &rerr) || !errors.Is(err, ErrResidency) {
+		t.Fatalf("CheckResidency = %v, want a *ResidencyError", err)
+	}
+	// rules apply in name order
+	if rerr.Rule != "a-eu" || rerr.Node != "us-a" {
+		t.Fatalf("violation %+v, want us-a under a-eu", *rerr)
+	}
+	if err := r.CheckResidency("us/1", []string{"us-a"}); err != nil {
+		t.Fatalf("unrestricted key rejected: %v", err)
+	}
+
+	r.UpdateResidency("a-eu", nil)
+	r.UpdateResidency("b-pii", nil)
+	if err := r.CheckResidency("eu/pii/1", []string{"us-a"}); err != nil {
+		t.Fatalf("removed rules still apply: %v", err)
+	}
+	if got := r.GetReplicaList("eu/pii/1", 2); len(got) != 2 {
+		t.Fatalf("replicas = %v after the rules were removed, want both nodes", got)
+	}
+}
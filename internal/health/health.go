@@ -0,0 +1,231 @@
+// internal/health/health.go
+package health
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// Tracker decides which storage nodes are healthy from three signals:
+// active gRPC health checks, the error rate of the requests the proxy sends
+// (passive checks), and optionally a phi-accrual detector fed by the
+// health-check responses. Nodes it has never seen are healthy.
+type Tracker struct {
+	// Interval is the time between active health checks of every node;
+	// Timeout bounds each check.
+	Interval time.Duration
+	Timeout  time.Duration
+	// ErrorThreshold is the exponentially weighted error rate, between 0
+	// and 1, above which a node is unhealthy once it has MinSamples
+	// observations.
+	ErrorThreshold float64
+	MinSamples     int
+	// PhiThreshold enables the phi-accrual detector if positive: a node is
+	// unhealthy once the suspicion that its health checks stopped
+	// answering exceeds it. 8 means a one in 10^8 chance of a false alarm
+	// under the observed inter-arrival distribution.
+	PhiThreshold float64
+
+	mu    sync.Mutex
+	nodes map[string]*nodeState
+}
+
+// errorAlpha is the weight of the newest observation in the error rate.
+const errorAlpha = 0.2
+
+type nodeState struct {
+	checked   bool // at least one active check completed
+	checkOK   bool // result of the latest active check
+	errRate   float64
+	samples   int
+	heartbeat *phiDetector
+}
+
+// NewTracker returns a tracker checking every two seconds that marks a node
+// unhealthy at a 50% error rate, with phi accrual disabled.
+func NewTracker() *Tracker {
+	return &Tracker{
+		Interval:       2 * time.Second,
+		Timeout:        time.Second,
+		ErrorThreshold: 0.5,
+		MinSamples:     5,
+		nodes:          make(map[string]*nodeState),
+	}
+}
+
+// state returns node's state, creating it if needed. Caller holds t.mu.
+func (t *Tracker) state(node string) *nodeState {
+	s, ok := t.nodes[node]
+	if !ok {
+		s = &nodeState{heartbeat: newPhiDetector()}
+		t.nodes[node] = s
+	}
+	return s
+}
+
+// Observe records the outcome of a request to node. Only failures that say
+// something about the node, see IsNodeFailure, count against it.
+func (t *Tracker) Observe(node string, err error) {
+	if t == nil {
+		return
+	}
+	failed := IsNodeFailure(err)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.state(node)
+	sample := 0.0
+	if failed {
+		sample = 1
+	}
+	s.errRate = errorAlpha*sample + (1-errorAlpha)*s.errRate
+	s.samples++
+}
+
+// Healthy reports whether node is considered up. A nil tracker considers
+// every node healthy.
+func (t *Tracker) Healthy(node string) bool {
+	if t == nil {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.nodes[node]
+	if !ok {
+		return true
+	}
+	if s.checked && !s.checkOK {
+		return false
+	}
+	if s.samples >= t.MinSamples && s.errRate > t.ErrorThreshold {
+		return false
+	}
+	if t.PhiThreshold > 0 && s.heartbeat.phi(time.Now()) > t.PhiThreshold {
+		return false
+	}
+	return true
+}
+
+// Run health-checks the nodes returned by nodes every Interval until ctx is
+// done.
+func (t *Tracker) Run(ctx context.Context, nodes func() []string) {
+	ticker := time.NewTicker(t.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var wg sync.WaitGroup
+			for _, node := range nodes() {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					t.check(ctx, node)
+				}()
+			}
+			wg.Wait()
+		}
+	}
+}
+
+// check runs one active health check of node.
+func (t *Tracker) check(ctx context.Context, node string) {
+	ctx, cancel := context.WithTimeout(ctx, t.Timeout)
+	defer cancel()
+	ok := false
+	conn, err := grpc.DialContext(ctx, node, grpc.WithInsecure(), grpc.WithBlock())
+	if err == nil {
+		resp, cerr := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+		conn.Close()
+		ok = cerr == nil && resp.Status == healthpb.HealthCheckResponse_SERVING
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.state(node)
+	s.checked = true
+	s.checkOK = ok
+	if ok {
+		s.heartbeat.beat(time.Now())
+		// a passing check is a success, so a node skipped for its error
+		// rate can recover
+		s.errRate *= 1 - errorAlpha
+	}
+}
+
+// IsNodeFailure reports whether err means the node could not serve the
+// request at all, as opposed to rejecting it.
+func IsNodeFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Aborted, codes.Internal:
+		return true
+	}
+	return false
+}
+
+// phiDetector is a phi-accrual failure detector: it keeps a window of
+// heartbeat inter-arrival times and expresses how unlikely the current
+// silence is, assuming normally distributed arrivals, as
+// phi = -log10(P(a heartbeat is still to come)).
+type phiDetector struct {
+	last      time.Time
+	intervals []float64 // seconds, oldest first
+}
+
+const (
+	// phiWindow is the number of inter-arrival times kept.
+	phiWindow = 100
+	// phiMinStdDev keeps very regular heartbeats from making phi jumpy.
+	phiMinStdDev = 0.1
+)
+
+func newPhiDetector() *phiDetector {
+	return &phiDetector{}
+}
+
+func (d *phiDetector) beat(now time.Time) {
+	if !d.last.IsZero() {
+		d.intervals = append(d.intervals, now.Sub(d.last).Seconds())
+		if len(d.intervals) > phiWindow {
+			d.intervals = d.intervals[1:]
+		}
+	}
+	d.last = now
+}
+
+// phi returns the suspicion level at now; 0 until there is a history.
+func (d *phiDetector) phi(now time.Time) float64 {
+	if len(d.intervals) < 2 {
+		return 0
+	}
+	var mean, sq float64
+	for _, v := range d.intervals {
+		mean += v
+	}
+	mean /= float64(len(d.intervals))
+	for _, v := range d.intervals {
+		sq += (v - mean) * (v - mean)
+	}
+	std := math.Max(math.Sqrt(sq/float64(len(d.intervals))), phiMinStdDev)
+	elapsed := now.Sub(d.last).Seconds()
+	// P(interval > elapsed) under N(mean, std)
+	pLater := 0.5 * math.Erfc((elapsed-mean)/(std*math.Sqrt2))
+	if pLater < 1e-300 {
+		pLater = 1e-300
+	}
+	return -math.Log10(pLater)
+}
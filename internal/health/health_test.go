@@ -0,0 +1,178 @@
+// internal/health/health_test.go
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsNodeFailure(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{context.DeadlineExceeded, true},
+		{fmt.Errorf("put: %w", context.DeadlineExceeded), true},
+		{status.Error(codes.Unavailable, "connection refused"), true},
+		{status.Error(codes.DeadlineExceeded, "slow"), true},
+		{status.Error(codes.Aborted, "aborted"), true},
+		{status.Error(codes.Internal, "internal"), true},
+		// the node answered, it just refused the request
+		{status.Error(codes.FailedPrecondition, "stale epoch"), false},
+		{status.Error(codes.NotFound, "no key"), false},
+		{status.Error(codes.InvalidArgument, "bad key"), false},
+		{status.Error(codes.ResourceExhausted, "throttled"), false},
+		{status.Error(codes.Canceled, "client went away"), false},
+		{errors.New("unknown"), false},
+	} {
+		if got := IsNodeFailure(tc.err); got != tc.want {
+			t.Errorf("IsNodeFailure(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestPhiGrowsWithMissedHeartbeats(t *testing.T) {
+	d := newPhiDetector()
+	start := time.Now()
+	if phi := d.phi(start); phi != 0 {
+		t.Fatalf("phi without history = %v, want 0", phi)
+	}
+	for i := 0; i <= 10; i++ {
+		d.beat(start.Add(time.Duration(i) * time.Second))
+	}
+	last := start.Add(10 * time.Second)
+
+	prev := -1.0
+	for _, tc := range []struct {
+		silence  time.Duration
+		min, max float64
+	}{
+		{0, 0, 1},
+		{time.Second, 0, 1}, // a heartbeat is due
+		{1500 * time.Millisecond, 1, 8},
+		{2 * time.Second, 8, 1000}, // ten missed standard deviations
+		{10 * time.Second, 8, 1000},
+	} {
+		phi := d.phi(last.Add(tc.silence))
+		if phi < tc.min || phi > tc.max {
+			t.Errorf("phi after %v of silence = %v, want in [%v, %v]", tc.silence, phi, tc.min, tc.max)
+		}
+		if phi < prev {
+			t.Errorf("phi after %v of silence = %v, below %v for a shorter silence", tc.silence, phi, prev)
+		}
+		prev = phi
+	}
+}
+
+func TestTrackerErrorRate(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "down")
+	rejected := status.Error(codes.FailedPrecondition, "stale epoch")
+	for _, tc := range []struct {
+		name    string
+		errs    []error
+		healthy bool
+	}{
+		{"never seen", nil, true},
+		{"successes", []error{nil, nil, nil, nil, nil}, true},
+		{"too few samples", []error{unavailable, unavailable, unavailable, unavailable}, true},
+		{"failing", []error{unavailable, unavailable, unavailable, unavailable, unavailable}, false},
+		{"rejections do not count", []error{rejected, rejected, rejected, rejected, rejected}, true},
+		{"occasional failures", []error{nil, unavailable, nil, nil, unavailable, nil, nil}, true},
+		{"recovered by successes", []error{unavailable, unavailable, unavailable, unavailable, unavailable, nil, nil, nil, nil}, true},
+	} {
+		tr := NewTracker()
+		for _, err := range tc.errs {
+			tr.Observe("n", err)
+		}
+		if got := tr.Healthy("n"); got != tc.healthy {
+			tr.mu.Lock()
+			rate := 0.0
+			if s, ok := tr.nodes["n"]; ok {
+				rate = s.errRate
+			}
+			tr.mu.Unlock()
+			t.Errorf("%s: Healthy = %v at error rate %.2f, want %v", tc.name, got, rate, tc.healthy)
+		}
+	}
+
+	var nilTracker *Tracker
+	nilTracker.Observe("n", unavailable)
+	if !nilTracker.Healthy("n") {
+		t.Error("a nil tracker marked a node down")
+	}
+}
+
+func TestTrackerActiveChecks(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := grpchealth.NewServer()
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	go srv.Serve(lis)
+	defer srv.Stop()
+	node := lis.Addr().String()
+
+	tr := NewTracker()
+	ctx := context.Background()
+	// failed requests mark the node down until its checks pass again
+	for i := 0; i < tr.MinSamples; i++ {
+		tr.Observe(node, status.Error(codes.Unavailable, "down"))
+	}
+	for _, step := range []struct {
+		status  healthpb.HealthCheckResponse_ServingStatus
+		healthy bool
+	}{
+		{healthpb.HealthCheckResponse_SERVING, false}, // error rate still high
+		{healthpb.HealthCheckResponse_SERVING, true},
+		{healthpb.HealthCheckResponse_NOT_SERVING, false},
+		{healthpb.HealthCheckResponse_SERVING, true},
+	} {
+		hs.SetServingStatus("", step.status)
+		tr.check(ctx, node)
+		if got := tr.Healthy(node); got != step.healthy {
+			t.Fatalf("after a %v check, Healthy = %v, want %v", step.status, got, step.healthy)
+		}
+	}
+
+	// a node that does not answer at all
+	srv.Stop()
+	tr.Timeout = 100 * time.Millisecond
+	tr.check(ctx, node)
+	if tr.Healthy(node) {
+		t.Fatal("unreachable node still healthy")
+	}
+}
+
+func TestTrackerPhiThreshold(t *testing.T) {
+	tr := NewTracker()
+	tr.PhiThreshold = 8
+	tr.mu.Lock()
+	s := tr.state("n")
+	s.checked, s.checkOK = true, true
+	// regular heartbeats that stopped five intervals ago
+	start := time.Now().Add(-15 * time.Second)
+	for i := 0; i <= 10; i++ {
+		s.heartbeat.beat(start.Add(time.Duration(i) * time.Second))
+	}
+	tr.mu.Unlock()
+	if tr.Healthy("n") {
+		t.Fatal("node healthy after five missed heartbeats")
+	}
+	tr.PhiThreshold = 0
+	if !tr.Healthy("n") {
+		t.Fatal("phi accrual not disabled by a zero threshold")
+	}
+}
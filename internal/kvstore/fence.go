@@ -0,0 +1,56 @@
+// internal/kvstore/fence.go
+package kvstore
+
+import (
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/proto"
+)
+
+// fence refuses a request for key routed at epoch if the server's ring is
+// newer and no longer lists it among the key's replicas, so a sender with
+// outdated routing cannot write to, or read from, a former owner while a
+// rebalance moves the key away. The refusal carries a Redirect to the
+// current owners.
+//
+// Requests at epoch 0 (migrations, tools), at the server's epoch or a newer
+// one (the server cannot judge those), and substitute writes hinted for a
+// down replica are let through.
+func (s *Service) fence(key string, epoch uint64, hintedFor string) error {
+	if s.Ring == nil || epoch == 0 || hintedFor != "" {
+		return nil
+	}
+	cur := s.Ring.Epoch()
+	if epoch >= cur {
+		return nil
+	}
+	owners := s.Ring.GetReplicaList(key, s.R)
+	for _, node := range owners {
+		if node == s.NodeID {
+			return nil
+		}
+	}
+	st := status.Newf(codes.FailedPrecondition, "not an owner of %q at epoch %d (request at %d)", key, cur, epoch)
+	if st, err := st.WithDetails(&proto.Redirect{Epoch: cur, Owners: owners}); err == nil {
+		return st.Err()
+	}
+	return st.Err()
+}
+
+// Redirected returns the redirect of a request a server refused because it
+// does not own the key at a newer epoch.
+func Redirected(err error) (*proto.Redirect, bool) {
+	var se interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &se) {
+		return nil, false
+	}
+	for _, d := range se.GRPCStatus().Details() {
+		if r, ok := d.(*proto.Redirect); ok {
+			return r, true
+		}
+	}
+	return nil, false
+}
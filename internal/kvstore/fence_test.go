@@ -0,0 +1,82 @@
+// internal/kvstore/fence_test.go
+package kvstore
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/proto"
+)
+
+func TestFence(t *testing.T) {
+	store, _ := newTestStore(t)
+	ring := hashring.New(20)
+	ring.Update([]byte(`{"nodes": ["a", "b", "c", "d"], "epoch": 5}`))
+	svc := NewService(store)
+	svc.Ring, svc.R = ring, 2
+
+	// a key this node owns and one it does not
+	var owned, foreign string
+	for i := 0; owned == "" || foreign == ""; i++ {
+		key := string(rune('a'+i%26)) + string(rune('a'+i/26))
+		if contains(ring.GetReplicaList(key, 2), "a") {
+			owned = key
+		} else {
+			foreign = key
+		}
+	}
+	svc.NodeID = "a"
+	ctx := context.Background()
+
+	for _, req := range []*proto.PutRequest{
+		{Key: foreign, Epoch: 0},                 // unfenced client
+		{Key: foreign, Epoch: 5},                 // routed at the current epoch
+		{Key: foreign, Epoch: 7},                 // the server is behind
+		{Key: owned, Epoch: 4},                   // stale, but still an owner
+		{Key: foreign, Epoch: 4, HintedFor: "b"}, // a substitute write
+	} {
+		if _, err := svc.Put(ctx, req); err != nil {
+			t.Fatalf("Put(%+v) refused: %v", req, err)
+		}
+	}
+
+	_, err := svc.Put(ctx, &proto.PutRequest{Key: foreign, Value: []byte("v"), Epoch: 4})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("stale Put = %v, want FailedPrecondition", err)
+	}
+	rd, ok := Redirected(err)
+	if !ok {
+		t.Fatalf("stale Put error %v carries no redirect", err)
+	}
+	if want := ring.GetReplicaList(foreign, 2); rd.Epoch != 5 || !equalStrings(rd.Owners, want) {
+		t.Fatalf("redirect = epoch %d to %v, want epoch 5 to %v", rd.Epoch, rd.Owners, want)
+	}
+	if _, err := svc.Get(ctx, &proto.GetRequest{Key: foreign, Epoch: 4}); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("stale Get = %v, want FailedPrecondition", err)
+	}
+}
+
+func contains(slice []string, s string) bool {
+	for _, v := range slice {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -3,17 +3,60 @@ package kvstore
 
 import (
 	"bufio"
+	"context"
 	"encoding/binary"
+	"errors"
+	"io"
 	"os"
+	"sort"
 	"sync"
+	"time"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
+)
+
+// WAL record flags. Records written before versioning carry neither flag:
+// keyLen(uint32) | key | valLen(uint32) | val.
+const (
+	// versionedFlag in keyLen marks a record with a uint64 version after the key.
+	versionedFlag = 1 << 31
+	// tombstoneLen as valLen marks a delete record; no value bytes follow.
+	tombstoneLen = ^uint32(0)
 )
 
+// record is a stored value with the version that wrote it, or a tombstone
+// left by a delete at that version.
+type record struct {
+	value   []byte
+	version uint64
+	deleted bool
+}
+
+// DefaultTombstoneTTL is how long deletes are remembered by default. It must
+// outlast every path that can deliver an older copy of a deleted key: moves
+// (settle plus grace), hinted handoff and rebalance catch-up.
+const DefaultTombstoneTTL = 10 * time.Minute
+
+// tombstone is a delete awaiting garbage collection.
+type tombstone struct {
+	key     string
+	version uint64
+	at      time.Time
+}
+
 // KVStore holds the in-memory map and a write-ahead log on disk.
+// Writes are last-writer-wins by version: an older version never replaces a
+// newer one, which lets migrations copy data while live writes continue.
+// Deletes leave a tombstone at their version, so a late copy of an older
+// value does not bring the key back; CollectTombstones removes them once
+// no such copy can arrive anymore.
 type KVStore struct {
-	mu     sync.RWMutex
-	data   map[string][]byte
-	wal    *os.File
-	writer *bufio.Writer
+	mu         sync.RWMutex
+	data       map[string]record
+	index      []string    // the live keys of data, sorted, for Scan and Keys
+	tombstones []tombstone // in deletion order
+	wal        *os.File
+	writer     *bufio.Writer
 }
 
 // NewWALStore opens/creates the WAL file and returns a store.
@@ -23,7 +66,7 @@ func NewWALStore(path string) (*KVStore, error) {
 		return nil, err
 	}
 	return &KVStore{
-		data:   make(map[string][]byte),
+		data:   make(map[string]record),
 		wal:    f,
 		writer: bufio.NewWriter(f),
 	}, nil
@@ -40,41 +83,83 @@ func (s *KVStore) Replay() error {
 	}
 	reader := bufio.NewReader(s.wal)
 	for {
-		// Each record: keyLen(uint32) | key bytes | valLen(uint32) | val bytes
+		// Each record: keyLen(uint32) | key bytes | [version(uint64)] | valLen(uint32) | val bytes
 		var keyLen uint32
 		if err := binary.Read(reader, binary.BigEndian, &keyLen); err != nil {
 			break // EOF
 		}
-		key := make([]byte, keyLen)
-		if _, err := reader.Read(key); err != nil {
+		versioned := keyLen&versionedFlag != 0
+		key := make([]byte, keyLen&^versionedFlag)
+		if _, err := io.ReadFull(reader, key); err != nil {
 			return err
 		}
+		var version uint64
+		if versioned {
+			if err := binary.Read(reader, binary.BigEndian, &version); err != nil {
+				return err
+			}
+		}
 		var valLen uint32
 		if err := binary.Read(reader, binary.BigEndian, &valLen); err != nil {
 			return err
 		}
+		if valLen == tombstoneLen {
+			s.data[string(key)] = record{version: version, deleted: true}
+			s.tombstones = append(s.tombstones, tombstone{key: string(key), version: version, at: time.Now()})
+			continue
+		}
 		val := make([]byte, valLen)
-		if _, err := reader.Read(val); err != nil {
+		if _, err := io.ReadFull(reader, val); err != nil {
 			return err
 		}
-		s.data[string(key)] = val
+		s.data[string(key)] = record{value: val, version: version}
+	}
+	s.index = make([]string, 0, len(s.data))
+	for k, r := range s.data {
+		if !r.deleted {
+			s.index = append(s.index, k)
+		}
 	}
+	sort.Strings(s.index)
 	return nil
 }
 
-// Append writes a key/value pair to the WAL.
-func (s *KVStore) Append(key string, value []byte) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// indexKey adds a new key to the index. Caller holds s.mu.
+func (s *KVStore) indexKey(key string) {
+	i := sort.SearchStrings(s.index, key)
+	s.index = append(s.index, "")
+	copy(s.index[i+1:], s.index[i:])
+	s.index[i] = key
+}
+
+// unindexKey removes a key from the index. Caller holds s.mu.
+func (s *KVStore) unindexKey(key string) {
+	i := sort.SearchStrings(s.index, key)
+	if i < len(s.index) && s.index[i] == key {
+		s.index = append(s.index[:i], s.index[i+1:]...)
+	}
+}
 
-	// Write lengths and bytes
-	if err := binary.Write(s.writer, binary.BigEndian, uint32(len(key))); err != nil {
+// appendRecord writes a versioned record to the WAL. A nil value writes a
+// tombstone. Caller holds s.mu.
+func (s *KVStore) appendRecord(key string, value []byte, version uint64) error {
+	if uint32(len(key))&versionedFlag != 0 {
+		return errors.New("key too long")
+	}
+	if err := binary.Write(s.writer, binary.BigEndian, uint32(len(key))|versionedFlag); err != nil {
 		return err
 	}
 	if _, err := s.writer.WriteString(key); err != nil {
 		return err
 	}
-	if err := binary.Write(s.writer, binary.BigEndian, uint32(len(value))); err != nil {
+	if err := binary.Write(s.writer, binary.BigEndian, version); err != nil {
+		return err
+	}
+	valLen := uint32(len(value))
+	if value == nil {
+		valLen = tombstoneLen
+	}
+	if err := binary.Write(s.writer, binary.BigEndian, valLen); err != nil {
 		return err
 	}
 	if _, err := s.writer.Write(value); err != nil {
@@ -83,17 +168,184 @@ func (s *KVStore) Append(key string, value []byte) error {
 	return s.writer.Flush()
 }
 
-// Set updates the in-memory map after WAL append.
-func (s *KVStore) Set(key string, value []byte) {
-	s.data[key] = value
+// Put logs and stores value at version unless the store already holds a
+// newer version of key, or a newer delete. It reports whether the write was
+// applied.
+func (s *KVStore) Put(key string, value []byte, version uint64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur, exists := s.data[key]
+	if exists && cur.version > version {
+		return false, nil
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	if err := s.appendRecord(key, value, version); err != nil {
+		return false, err
+	}
+	s.data[key] = record{value: value, version: version}
+	if !exists || cur.deleted {
+		s.indexKey(key)
+	}
+	return true, nil
+}
+
+// Delete logs a tombstone for key at version unless the store holds a newer
+// version of it. A zero version deletes whatever is stored, at its version.
+// It reports whether a value was removed.
+func (s *KVStore) Delete(key string, version uint64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur, ok := s.data[key]
+	if version == 0 {
+		if !ok || cur.deleted {
+			return false, nil
+		}
+		version = cur.version
+	}
+	if ok && (cur.version > version || (cur.deleted && cur.version == version)) {
+		return false, nil
+	}
+	if err := s.appendRecord(key, nil, version); err != nil {
+		return false, err
+	}
+	s.data[key] = record{version: version, deleted: true}
+	s.tombstones = append(s.tombstones, tombstone{key: key, version: version, at: time.Now()})
+	if ok && !cur.deleted {
+		s.unindexKey(key)
+		return true, nil
+	}
+	return false, nil
+}
+
+// CollectTombstones forgets the deletes made before cutoff, returning how
+// many it dropped. Their WAL records stay, so a restart keeps them for
+// another round.
+func (s *KVStore) CollectTombstones(cutoff time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for len(s.tombstones) > 0 && s.tombstones[0].at.Before(cutoff) {
+		t := s.tombstones[0]
+		s.tombstones = s.tombstones[1:]
+		// skip tombstones since replaced by a write or a newer delete
+		if r, ok := s.data[t.key]; ok && r.deleted && r.version == t.version {
+			delete(s.data, t.key)
+			n++
+		}
+	}
+	return n
+}
+
+// RunTombstoneGC collects tombstones older than ttl until ctx is done.
+func (s *KVStore) RunTombstoneGC(ctx context.Context, ttl time.Duration) {
+	ticker := time.NewTicker(ttl / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.CollectTombstones(time.Now().Add(-ttl))
+		}
+	}
 }
 
 // Get retrieves a value from the in-memory map.
 func (s *KVStore) Get(key string) ([]byte, bool) {
-	v, ok := s.data[key]
+	v, _, ok := s.GetVersion(key)
 	return v, ok
 }
 
+// GetVersion retrieves a value together with its version.
+func (s *KVStore) GetVersion(key string) ([]byte, uint64, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.data[key]
+	if !ok || r.deleted {
+		return nil, 0, false
+	}
+	return r.value, r.version, true
+}
+
+// Lookup returns key's value and version, or with deleted set the version
+// of the delete that removed it. ok is false if the store holds neither.
+func (s *KVStore) Lookup(key string) (value []byte, version uint64, deleted, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.data[key]
+	return r.value, r.version, r.deleted, ok
+}
+
+// DeletedKeys returns, in order, the keys after cursor whose tombstones are
+// still kept and for which match is true.
+func (s *KVStore) DeletedKeys(match func(key string) bool, cursor string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0)
+	for _, t := range s.tombstones {
+		// skip tombstones since replaced by a write or a newer delete
+		if r, ok := s.data[t.key]; ok && r.deleted && r.version == t.version && t.key > cursor && match(t.key) {
+			keys = append(keys, t.key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Scan returns up to limit entries whose ring hash lies in rng, in key order,
+// starting after cursor. next is empty once the range is exhausted.
+func (s *KVStore) Scan(rng hashring.Range, cursor string, limit int) (entries []Entry, next string) {
+	n := 0
+	if limit > 0 {
+		n = limit + 1 // one more tells whether the range goes on
+	}
+	keys := s.Keys(rng.ContainsKey, cursor, n)
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+		next = keys[limit-1]
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entries = make([]Entry, 0, len(keys))
+	for _, k := range keys {
+		if r, ok := s.data[k]; ok && !r.deleted {
+			entries = append(entries, Entry{Key: k, Value: r.value, Version: r.version})
+		}
+	}
+	return entries, next
+}
+
+// Keys returns, in order, up to limit keys after cursor for which match is
+// true; all of them if limit is 0.
+func (s *KVStore) Keys(match func(key string) bool, cursor string, limit int) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0)
+	i := sort.SearchStrings(s.index, cursor)
+	if i < len(s.index) && s.index[i] == cursor {
+		i++
+	}
+	for _, k := range s.index[i:] {
+		if limit > 0 && len(keys) == limit {
+			break
+		}
+		if match(k) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Entry is a key/value pair returned by Scan.
+type Entry struct {
+	Key     string
+	Value   []byte
+	Version uint64
+}
+
 // Close should be called when shutting down to close the WAL file.
 func (s *KVStore) Close() error {
 	if err := s.writer.Flush(); err != nil {
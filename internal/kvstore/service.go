@@ -3,7 +3,9 @@ package kvstore
 
 import (
 	"context"
+	"time"
 
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
 	"github.com/ksharma120497/adaptive-geo-distributed-database/proto"
 )
 
@@ -11,6 +13,13 @@ import (
 type Service struct {
 	*proto.UnimplementedKVServer
 	store *KVStore
+
+	// Ring, if set, is the server's view of the cluster: requests routed
+	// at an older epoch for keys NodeID does not own under it, with R
+	// replicas, are refused with a redirect.
+	Ring   *hashring.Ring
+	NodeID string
+	R      int
 }
 
 // NewService returns a new KV service wrapping the given store.
@@ -21,20 +30,66 @@ func NewService(s *KVStore) *Service {
 	}
 }
 
-// Put writes the key/value into the store.
+// Put writes the key/value into the store. Requests without a version are
+// stamped with the local clock.
 func (s *Service) Put(ctx context.Context, req *proto.PutRequest) (*proto.PutReply, error) {
-	if err := s.store.Append(req.Key, req.Value); err != nil {
+	if err := s.fence(req.Key, req.Epoch, req.HintedFor); err != nil {
 		return nil, err
 	}
-	s.store.Set(req.Key, req.Value)
-	return &proto.PutReply{Success: true}, nil
+	version := req.Version
+	if version == 0 {
+		version = uint64(time.Now().UnixNano())
+	}
+	applied, err := s.store.Put(req.Key, req.Value, version)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.PutReply{Success: true, Applied: applied, Version: version}, nil
 }
 
 // Get reads the value for a key from the store.
 func (s *Service) Get(ctx context.Context, req *proto.GetRequest) (*proto.GetReply, error) {
-	val, ok := s.store.Get(req.Key)
+	if err := s.fence(req.Key, req.Epoch, ""); err != nil {
+		return nil, err
+	}
+	val, version, ok := s.store.GetVersion(req.Key)
 	if !ok {
 		return &proto.GetReply{Found: false}, nil
 	}
-	return &proto.GetReply{Value: val, Found: true}, nil
+	return &proto.GetReply{Value: val, Found: true, Version: version}, nil
+}
+
+// Delete removes a key from the store. Requests without a version are
+// stamped with the local clock, like Put.
+func (s *Service) Delete(ctx context.Context, req *proto.DeleteRequest) (*proto.DeleteReply, error) {
+	if err := s.fence(req.Key, req.Epoch, ""); err != nil {
+		return nil, err
+	}
+	version := req.Version
+	if version == 0 {
+		version = uint64(time.Now().UnixNano())
+	}
+	deleted, err := s.store.Delete(req.Key, version)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.DeleteReply{Deleted: deleted}, nil
+}
+
+// defaultScanLimit caps a Scan page when the request does not set a limit.
+const defaultScanLimit = 1000
+
+// Scan pages through the keys in a hash range.
+func (s *Service) Scan(ctx context.Context, req *proto.ScanRequest) (*proto.ScanReply, error) {
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultScanLimit
+	}
+	rng := hashring.Range{Start: req.StartHash, End: req.EndHash}
+	entries, next := s.store.Scan(rng, req.Cursor, limit)
+	reply := &proto.ScanReply{NextCursor: next, Entries: make([]*proto.KeyValue, len(entries))}
+	for i, e := range entries {
+		reply.Entries[i] = &proto.KeyValue{Key: e.Key, Value: e.Value, Version: e.Version}
+	}
+	return reply, nil
 }
@@ -0,0 +1,188 @@
+// internal/kvstore/transfer.go
+package kvstore
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/ratelimit"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/proto"
+)
+
+const (
+	// defaultChunkSize is the number of entries per TransferChunk when the
+	// request does not set one.
+	defaultChunkSize = 256
+	// importDialTimeout bounds how long Import waits to reach the source.
+	importDialTimeout = 2 * time.Second
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Checksum returns the CRC-32C of a chunk's entries. Each entry contributes
+// its length-prefixed key and value, its version and whether it is a
+// delete, so entry boundaries are covered too.
+func Checksum(entries []*proto.KeyValue) uint32 {
+	var crc uint32
+	var buf [8]byte
+	for _, e := range entries {
+		binary.BigEndian.PutUint32(buf[:4], uint32(len(e.Key)))
+		crc = crc32.Update(crc, castagnoli, buf[:4])
+		crc = crc32.Update(crc, castagnoli, []byte(e.Key))
+		binary.BigEndian.PutUint32(buf[:4], uint32(len(e.Value)))
+		crc = crc32.Update(crc, castagnoli, buf[:4])
+		crc = crc32.Update(crc, castagnoli, e.Value)
+		binary.BigEndian.PutUint64(buf[:], e.Version)
+		crc = crc32.Update(crc, castagnoli, buf[:])
+		if e.Deleted {
+			crc = crc32.Update(crc, castagnoli, []byte{1})
+		}
+	}
+	return crc
+}
+
+// Transfer streams the selected keys in checksummed chunks, pacing the
+// stream to the requested bandwidth. Deletes still remembered by a tombstone
+// are streamed too, so a key deleted on the source during a migration is
+// deleted on the destination as well.
+func (s *Service) Transfer(req *proto.TransferRequest, stream proto.KV_TransferServer) error {
+	ranges := make([]hashring.Range, len(req.Ranges))
+	for i, r := range req.Ranges {
+		ranges[i] = hashring.Range{Start: r.Start, End: r.End}
+	}
+	ranges = mergeRanges(ranges)
+	wanted := make(map[string]struct{}, len(req.Keys))
+	for _, k := range req.Keys {
+		wanted[k] = struct{}{}
+	}
+	match := func(key string) bool {
+		for _, prefix := range req.ExcludePrefixes {
+			if strings.HasPrefix(key, prefix) {
+				return false
+			}
+		}
+		if _, ok := wanted[key]; ok {
+			return true
+		}
+		return strings.HasPrefix(key, req.Prefix) && inRanges(ranges, hashring.HashKey(key))
+	}
+	keys := append(s.store.Keys(match, req.Cursor, 0), s.store.DeletedKeys(match, req.Cursor)...)
+	sort.Strings(keys)
+
+	chunkSize := int(req.ChunkSize)
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+	limiter := ratelimit.New(float64(req.BytesPerSec))
+	for start := 0; start < len(keys); start += chunkSize {
+		end := start + chunkSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		chunk := &proto.TransferChunk{Cursor: keys[end-1]}
+		size := 0
+		for _, k := range keys[start:end] {
+			// keys collected since the listing are simply skipped
+			v, version, deleted, ok := s.store.Lookup(k)
+			if ok && version >= req.MinVersion {
+				chunk.Entries = append(chunk.Entries, &proto.KeyValue{Key: k, Value: v, Version: version, Deleted: deleted})
+				size += len(k) + len(v)
+			}
+		}
+		chunk.Checksum = Checksum(chunk.Entries)
+		if err := limiter.Wait(stream.Context(), size); err != nil {
+			return err
+		}
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeRanges sorts ranges by start and merges the overlapping ones, for
+// inRanges.
+func mergeRanges(ranges []hashring.Range) []hashring.Range {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+	merged := ranges[:0]
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && uint64(r.Start) <= uint64(merged[n-1].End)+1 {
+			if r.End > merged[n-1].End {
+				merged[n-1].End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// inRanges reports whether h lies in one of the sorted, disjoint ranges.
+func inRanges(ranges []hashring.Range, h uint32) bool {
+	// the first range starting after h; h can only lie in the one before
+	i := sort.Search(len(ranges), func(i int) bool { return ranges[i].Start > h })
+	return i > 0 && ranges[i-1].Contains(h)
+}
+
+// Import pulls a transfer from req.Source, verifies every chunk and applies
+// it, reporting the resume cursor after each chunk. Entries and deletes are
+// applied last writer wins, so a copy never overwrites a newer live write.
+func (s *Service) Import(req *proto.ImportRequest, stream proto.KV_ImportServer) error {
+	ctx := stream.Context()
+	dialCtx, cancel := context.WithTimeout(ctx, importDialTimeout)
+	conn, err := grpc.DialContext(dialCtx, req.Source, grpc.WithInsecure(), grpc.WithBlock())
+	cancel()
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "dial source %s: %v", req.Source, err)
+	}
+	defer conn.Close()
+
+	src, err := proto.NewKVClient(conn).Transfer(ctx, req.Transfer)
+	if err != nil {
+		return fmt.Errorf("transfer from %s: %w", req.Source, err)
+	}
+	progress := &proto.ImportProgress{Cursor: req.Transfer.GetCursor()}
+	for {
+		chunk, err := src.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("receive from %s: %w", req.Source, err)
+		}
+		if sum := Checksum(chunk.Entries); sum != chunk.Checksum {
+			return status.Errorf(codes.DataLoss, "chunk ending at %q: checksum %08x, want %08x",
+				chunk.Cursor, sum, chunk.Checksum)
+		}
+		for _, e := range chunk.Entries {
+			if e.Deleted {
+				// a zero version would delete whatever is stored here
+				if e.Version > 0 {
+					_, err = s.store.Delete(e.Key, e.Version)
+				}
+			} else {
+				_, err = s.store.Put(e.Key, e.Value, e.Version)
+			}
+			if err != nil {
+				return err
+			}
+			progress.Keys++
+			progress.Bytes += uint64(len(e.Key) + len(e.Value))
+		}
+		progress.Cursor = chunk.Cursor
+		if err := stream.Send(progress); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,42 @@
+// internal/kvstore/transfer_test.go
+package kvstore
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
+)
+
+func TestInRanges(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for round := 0; round < 100; round++ {
+		ranges := make([]hashring.Range, rng.Intn(20))
+		for i := range ranges {
+			a, b := rng.Uint32()>>8, rng.Uint32()>>8
+			if a > b {
+				a, b = b, a
+			}
+			ranges[i] = hashring.Range{Start: a, End: b}
+		}
+		ranges = append(ranges, hashring.Range{Start: ^uint32(0) - 5, End: ^uint32(0)})
+		linear := append([]hashring.Range(nil), ranges...)
+		merged := mergeRanges(ranges)
+
+		for i := 0; i < 1000; i++ {
+			h := rng.Uint32() >> 8
+			if i%10 == 0 && len(linear) > 0 {
+				// probe the edges too
+				r := linear[rng.Intn(len(linear))]
+				h = []uint32{r.Start, r.End, r.Start - 1, r.End + 1}[(i/10)%4]
+			}
+			want := false
+			for _, r := range linear {
+				want = want || r.Contains(h)
+			}
+			if got := inRanges(merged, h); got != want {
+				t.Fatalf("inRanges(%v, %d) = %v, want %v", merged, h, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// internal/membership/controller.go
+package membership
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/metadata"
+)
+
+// Controller derives the ring's node list and regions from the storage
+// servers registered under /nodes/. Changes are staged as /ring/proposed,
+// so the rebalancer moves the data before routing switches over.
+//
+// A registered node joins at the next reconciliation. A node whose
+// registration disappears stays in the ring for RemoveDelay, so a restart
+// or a short network blip does not trigger two rebalances. The capacity a
+// node registers with becomes its ring weight.
+type Controller struct {
+	md   metadata.Store
+	ring *hashring.Ring
+
+	// RemoveDelay is how long a ring node must stay unregistered before it
+	// is removed.
+	RemoveDelay time.Duration
+	// Interval is how often membership is reconciled.
+	Interval time.Duration
+
+	watchOnce sync.Once
+	mu        sync.Mutex
+	nodes     map[string]metadata.NodeInfo // live registrations by ID
+	downSince map[string]time.Time         // unregistered ring nodes
+	proposed  string                       // last configuration proposed
+}
+
+// NewController returns a controller with a one-minute removal delay.
+func NewController(md metadata.Store, r *hashring.Ring) *Controller {
+	return &Controller{
+		md:          md,
+		ring:        r,
+		RemoveDelay: time.Minute,
+		Interval:    5 * time.Second,
+		nodes:       make(map[string]metadata.NodeInfo),
+		downSince:   make(map[string]time.Time),
+	}
+}
+
+// Run reconciles membership every Interval until ctx is done.
+func (c *Controller) Run(ctx context.Context) {
+	c.watchOnce.Do(func() {
+		c.md.WatchNodes(c.update)
+		c.md.WatchProposedRingConfig(c.staged)
+	})
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.reconcile(time.Now()); err != nil {
+				log.Printf("membership: %v", err)
+			}
+		}
+	}
+}
+
+// update applies a change under /nodes/.
+func (c *Controller) update(id string, raw []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(raw) == 0 {
+		delete(c.nodes, id)
+		return
+	}
+	var info metadata.NodeInfo
+	if err := json.Unmarshal(raw, &info); err != nil || info.Addr == "" {
+		log.Printf("membership: ignoring invalid registration of %s", id)
+		return
+	}
+	c.nodes[id] = info
+}
+
+// staged forgets the controller's last proposal once another configuration
+// replaces it under /ring/proposed, so it is proposed again if still needed.
+func (c *Controller) staged(config []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if string(config) != c.proposed {
+		c.proposed = ""
+	}
+}
+
+// reconcile proposes a ring configuration matching the live nodes, if it
+// differs from the current one.
+func (c *Controller) reconcile(now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cur := c.ring.Config()
+	live := make(map[string]metadata.NodeInfo, len(c.nodes))
+	for _, info := range c.nodes {
+		live[info.Addr] = info
+	}
+
+	cfg := cur
+	cfg.Nodes = nil
+	cfg.Regions = make(map[string]string)
+	cfg.Weights = nil
+	for _, node := range cur.Nodes {
+		if _, ok := live[node]; ok {
+			delete(c.downSince, node)
+		} else {
+			since, ok := c.downSince[node]
+			if !ok {
+				since = now
+				c.downSince[node] = now
+			}
+			if now.Sub(since) >= c.RemoveDelay {
+				continue
+			}
+		}
+		cfg.Nodes = append(cfg.Nodes, node)
+		if region, ok := cur.Regions[node]; ok {
+			cfg.Regions[node] = region
+		}
+	}
+	for node := range c.downSince {
+		if !contains(cur.Nodes, node) {
+			delete(c.downSince, node)
+		}
+	}
+	var joined []string
+	for addr := range live {
+		if !contains(cfg.Nodes, addr) {
+			joined = append(joined, addr)
+		}
+	}
+	sort.Strings(joined)
+	cfg.Nodes = append(cfg.Nodes, joined...)
+	for addr, info := range live {
+		if info.Region != "" {
+			cfg.Regions[addr] = info.Region
+		}
+	}
+	// weights follow the advertised capacities; nodes advertising none, or
+	// down, keep theirs
+	for _, node := range cfg.Nodes {
+		w, ok := cur.Weights[node]
+		if info, up := live[node]; up && info.Capacity > 0 && cfg.Algorithm != hashring.AlgorithmJump {
+			w, ok = info.Capacity, true
+		}
+		if ok {
+			if cfg.Weights == nil {
+				cfg.Weights = make(map[string]float64)
+			}
+			cfg.Weights[node] = w
+		}
+	}
+
+	if len(cfg.Nodes) == 0 || sameConfig(cur, cfg) {
+		return nil
+	}
+	buf, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if string(buf) == c.proposed {
+		// still staged; the rebalancer retries it until it is published
+		return nil
+	}
+	if err := c.md.ProposeRingConfig(buf); err != nil {
+		return err
+	}
+	c.proposed = string(buf)
+	var removed []string
+	for _, node := range cur.Nodes {
+		if !contains(cfg.Nodes, node) {
+			removed = append(removed, node)
+		}
+	}
+	log.Printf("membership: proposed %d nodes (joined %v, removed %v)", len(cfg.Nodes), joined, removed)
+	return nil
+}
+
+// sameConfig reports whether a and b have the same nodes, regions and
+// weights.
+func sameConfig(a, b hashring.Config) bool {
+	if len(a.Nodes) != len(b.Nodes) || len(a.Regions) != len(b.Regions) || len(a.Weights) != len(b.Weights) {
+		return false
+	}
+	for node, w := range a.Weights {
+		if bw, ok := b.Weights[node]; !ok || bw != w {
+			return false
+		}
+	}
+	for i := range a.Nodes {
+		if a.Nodes[i] != b.Nodes[i] {
+			return false
+		}
+	}
+	for node, region := range a.Regions {
+		if b.Regions[node] != region {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(slice []string, s string) bool {
+	for _, v := range slice {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+// internal/membership/controller_test.go
+package membership
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/metadata"
+)
+
+func TestControllerReproposesReplacedProposal(t *testing.T) {
+	md := metadata.NewMemory()
+	ring := hashring.New(20)
+	md.WatchRingConfig(ring.Update)
+	if _, err := md.SetRingConfig([]byte(`{"vnodes_per_node": 20, "nodes": ["a:1"]}`), metadata.AnyRevision); err != nil {
+		t.Fatal(err)
+	}
+	c := NewController(md, ring)
+	c.watchOnce.Do(func() {
+		md.WatchNodes(c.update)
+		md.WatchProposedRingConfig(c.staged)
+	})
+	ctx := context.Background()
+	for _, id := range []string{"a", "b"} {
+		if _, err := md.Register(ctx, metadata.NodeInfo{ID: id, Addr: id + ":1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	waitFor(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return len(c.nodes) == 2 && ring.Epoch() == 1
+	})
+
+	staged := func() hashring.Config {
+		t.Helper()
+		raw, ok := md.Get("/ring/proposed")
+		if !ok {
+			t.Fatal("nothing staged under /ring/proposed")
+		}
+		var cfg hashring.Config
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+	if err := c.reconcile(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if cfg := staged(); len(cfg.Nodes) != 2 {
+		t.Fatalf("proposed %v, want a:1 and b:1", cfg.Nodes)
+	}
+
+	// an operator's proposal replaces the controller's, which is proposed
+	// again at the next reconciliation rather than suppressed for good
+	md.ProposeRingConfig([]byte(`{"vnodes_per_node": 20, "nodes": ["a:1", "c:1"]}`))
+	waitFor(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.proposed == ""
+	})
+	if err := c.reconcile(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if cfg := staged(); len(cfg.Nodes) != 2 || cfg.Nodes[1] != "b:1" {
+		t.Fatalf("staged %v after reconciling, want a:1 and b:1", cfg.Nodes)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met after 5s")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestControllerWeightsFollowCapacity(t *testing.T) {
+	md := metadata.NewMemory()
+	ring := hashring.New(20)
+	md.WatchRingConfig(ring.Update)
+	cfg := `{"vnodes_per_node": 20, "nodes": ["a:1", "gone:1"], "weights": {"a:1": 1, "gone:1": 3}}`
+	if _, err := md.SetRingConfig([]byte(cfg), metadata.AnyRevision); err != nil {
+		t.Fatal(err)
+	}
+	c := NewController(md, ring)
+	c.RemoveDelay = 0
+	c.watchOnce.Do(func() { md.WatchNodes(c.update) })
+	ctx := context.Background()
+	md.Register(ctx, metadata.NodeInfo{ID: "a", Addr: "a:1", Capacity: 2})
+	md.Register(ctx, metadata.NodeInfo{ID: "b", Addr: "b:1", Capacity: 0.5})
+	waitFor(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return len(c.nodes) == 2 && ring.Epoch() == 1
+	})
+
+	if err := c.reconcile(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := md.Get("/ring/proposed")
+	var proposed hashring.Config
+	if err := json.Unmarshal(raw, &proposed); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{"a:1": 2, "b:1": 0.5}
+	if len(proposed.Weights) != len(want) {
+		t.Fatalf("proposed weights %v, want %v", proposed.Weights, want)
+	}
+	for node, w := range want {
+		if proposed.Weights[node] != w {
+			t.Fatalf("proposed weights %v, want %v", proposed.Weights, want)
+		}
+	}
+}
@@ -0,0 +1,424 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Memory is an in-process Store for tests and single-process clusters.
+// Watches see the same events in the same order as on etcd: each watch
+// first gets the current state of its keys, in key order, then every later
+// change, and runs its callback on its own goroutine. Its watches never
+// break, so they are always healthy.
+type Memory struct {
+	watchers
+
+	mu        sync.Mutex
+	rev       int64 // incremented by every change
+	kv        map[string][]byte
+	mod       map[string]int64 // revision of each key's last change
+	subs      []*subscription
+	elections map[string][]*memoryLeadership // candidates, leader first
+	leases    map[string]*memoryLease        // node registrations by key
+}
+
+// NewMemory returns an empty in-memory store.
+func NewMemory() *Memory {
+	m := &Memory{
+		kv:        make(map[string][]byte),
+		mod:       make(map[string]int64),
+		elections: make(map[string][]*memoryLeadership),
+		leases:    make(map[string]*memoryLease),
+	}
+	m.watchers = watchers{watch: m.watch}
+	return m
+}
+
+// subscription queues events for one watch callback.
+type subscription struct {
+	key    string
+	prefix bool
+	fn     func(event)
+
+	mu     sync.Mutex
+	queue  []event
+	notify chan struct{}
+}
+
+func (s *subscription) matches(key string) bool {
+	if s.prefix {
+		return strings.HasPrefix(key, s.key)
+	}
+	return key == s.key
+}
+
+func (s *subscription) run() {
+	for range s.notify {
+		s.mu.Lock()
+		batch := s.queue
+		s.queue = nil
+		s.mu.Unlock()
+		for _, ev := range batch {
+			s.fn(ev)
+		}
+	}
+}
+
+func (m *Memory) watch(key string, prefix bool, fn func(event)) {
+	s := &subscription{key: key, prefix: prefix, fn: fn, notify: make(chan struct{}, 1)}
+	m.mu.Lock()
+	var keys []string
+	for k := range m.kv {
+		if s.matches(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s.queue = append(s.queue, event{key: k, value: m.kv[k]})
+	}
+	if len(s.queue) > 0 {
+		s.notify <- struct{}{}
+	}
+	m.subs = append(m.subs, s)
+	m.mu.Unlock()
+	go s.run()
+}
+
+// Watches reports every watch as healthy at the current revision.
+func (m *Memory) Watches() []WatchStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]WatchStatus, len(m.subs))
+	for i, s := range m.subs {
+		out[i] = WatchStatus{Key: s.key, Healthy: true, Revision: m.rev}
+	}
+	return out
+}
+
+// publish queues ev for every matching watch. Caller holds m.mu, so events
+// are queued in commit order.
+func (m *Memory) publish(ev event) {
+	for _, s := range m.subs {
+		if !s.matches(ev.key) {
+			continue
+		}
+		s.mu.Lock()
+		s.queue = append(s.queue, ev)
+		s.mu.Unlock()
+		select {
+		case s.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// put stores value under key. Caller holds m.mu.
+func (m *Memory) put(key string, value []byte) {
+	value = append([]byte(nil), value...)
+	m.rev++
+	m.kv[key] = value
+	m.mod[key] = m.rev
+	m.publish(event{key: key, value: value})
+}
+
+// del removes key; like etcd, deleting a missing key is not an event.
+// Caller holds m.mu.
+func (m *Memory) del(key string) {
+	if _, ok := m.kv[key]; !ok {
+		return
+	}
+	m.rev++
+	delete(m.kv, key)
+	delete(m.mod, key)
+	m.publish(event{key: key, deleted: true})
+}
+
+// Put stores value under any key, e.g. "/ring/proposed", as an operator
+// would with etcdctl.
+func (m *Memory) Put(key string, value []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.put(key, value)
+}
+
+// Delete removes any key.
+func (m *Memory) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.del(key)
+}
+
+// Get returns the value stored under key.
+func (m *Memory) Get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.kv[key]
+	return append([]byte(nil), v...), ok
+}
+
+// Replicas returns the override of key and its revision.
+func (m *Memory) Replicas(key string) ([]string, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	buf, ok := m.kv["/replicas/"+key]
+	if !ok {
+		return nil, 0, nil
+	}
+	var replicas []string
+	if err := json.Unmarshal(buf, &replicas); err != nil {
+		return nil, 0, err
+	}
+	return replicas, m.mod["/replicas/"+key], nil
+}
+
+// SetReplicas writes the replica list for key if the override's revision
+// is still rev, or unconditionally for AnyRevision.
+func (m *Memory) SetReplicas(key string, replicas []string, rev int64) (int64, error) {
+	buf, err := json.Marshal(replicas)
+	if err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if rev != AnyRevision && m.mod["/replicas/"+key] != rev {
+		return 0, ErrConflict
+	}
+	m.put("/replicas/"+key, buf)
+	return m.rev, nil
+}
+
+// ClearReplicas deletes the replica override of key.
+func (m *Memory) ClearReplicas(key string) error {
+	m.Delete("/replicas/" + key)
+	return nil
+}
+
+// ClearReplicasWithPrefix deletes the replica overrides of every key with
+// prefix and returns how many there were.
+func (m *Memory) ClearReplicasWithPrefix(prefix string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var keys []string
+	for k := range m.kv {
+		if strings.HasPrefix(k, "/replicas/"+prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		m.del(k)
+	}
+	return len(keys), nil
+}
+
+// SetPolicy stores a JSON-encoded placement policy under "/policies/<name>".
+func (m *Memory) SetPolicy(name string, policy []byte) error {
+	m.Put("/policies/"+name, policy)
+	return nil
+}
+
+// DeletePolicy removes the placement policy stored under name.
+func (m *Memory) DeletePolicy(name string) error {
+	m.Delete("/policies/" + name)
+	return nil
+}
+
+// SetResidencyRule stores a JSON-encoded residency rule under "/residency/<name>".
+func (m *Memory) SetResidencyRule(name string, rule []byte) error {
+	m.Put("/residency/"+name, rule)
+	return nil
+}
+
+// DeleteResidencyRule removes the residency rule stored under name.
+func (m *Memory) DeleteResidencyRule(name string) error {
+	m.Delete("/residency/" + name)
+	return nil
+}
+
+// RingConfig returns "/ring/config" and its revision.
+func (m *Memory) RingConfig() ([]byte, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cfg, ok := m.kv["/ring/config"]
+	if !ok {
+		return nil, 0, nil
+	}
+	return append([]byte(nil), cfg...), m.mod["/ring/config"], nil
+}
+
+// SetRingConfig publishes a new "/ring/config" with the next epoch if its
+// revision is still rev, removing a matching proposal atomically.
+func (m *Memory) SetRingConfig(config []byte, rev int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if rev != AnyRevision && m.mod["/ring/config"] != rev {
+		return 0, ErrConflict
+	}
+	stamped, err := withEpoch(config, m.kv["/ring/config"])
+	if err != nil {
+		return 0, err
+	}
+	proposed, ok := m.kv["/ring/proposed"]
+	m.put("/ring/config", stamped)
+	cur := m.rev
+	if ok && string(proposed) == string(config) {
+		m.del("/ring/proposed")
+	}
+	return cur, nil
+}
+
+// ProposeRingConfig stages config under "/ring/proposed".
+func (m *Memory) ProposeRingConfig(config []byte) error {
+	m.Put("/ring/proposed", config)
+	return nil
+}
+
+// memoryLease is a node registration in a Memory store.
+type memoryLease struct {
+	m    *Memory
+	key  string
+	once sync.Once
+	done chan struct{}
+}
+
+// Register puts node under "/nodes/<id>" until the lease is closed or
+// expired with ExpireNode.
+func (m *Memory) Register(ctx context.Context, node NodeInfo) (Lease, error) {
+	buf, err := json.Marshal(node)
+	if err != nil {
+		return nil, err
+	}
+	l := &memoryLease{m: m, key: "/nodes/" + node.ID, done: make(chan struct{})}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if old := m.leases[l.key]; old != nil {
+		old.end()
+	}
+	m.leases[l.key] = l
+	m.put(l.key, buf)
+	return l, nil
+}
+
+// ExpireNode ends a node's registration lease, as if the node had stopped
+// renewing it.
+func (m *Memory) ExpireNode(id string) {
+	m.mu.Lock()
+	l := m.leases["/nodes/"+id]
+	m.mu.Unlock()
+	if l != nil {
+		l.Close(context.Background())
+	}
+}
+
+// end closes the lease's Done channel. Caller holds m.mu.
+func (l *memoryLease) end() {
+	l.once.Do(func() { close(l.done) })
+}
+
+// Done is closed when the lease expired or was closed.
+func (l *memoryLease) Done() <-chan struct{} {
+	return l.done
+}
+
+// Close deletes the registration.
+func (l *memoryLease) Close(ctx context.Context) error {
+	l.m.mu.Lock()
+	defer l.m.mu.Unlock()
+	if l.m.leases[l.key] == l {
+		delete(l.m.leases, l.key)
+		l.m.del(l.key)
+	}
+	l.end()
+	return nil
+}
+
+// memoryLeadership is a candidacy in a Memory election.
+type memoryLeadership struct {
+	m       *Memory
+	name    string
+	value   string
+	elected chan struct{} // closed once the candidate leads
+	done    chan struct{} // closed when the candidacy ends
+}
+
+// Campaign blocks until the caller leads the named election or ctx is done.
+// Candidates are elected in the order they campaigned.
+func (m *Memory) Campaign(ctx context.Context, name, value string) (Leadership, error) {
+	l := &memoryLeadership{m: m, name: name, value: value, elected: make(chan struct{}), done: make(chan struct{})}
+	m.mu.Lock()
+	m.elections[name] = append(m.elections[name], l)
+	if len(m.elections[name]) == 1 {
+		close(l.elected)
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-l.elected:
+		return l, nil
+	case <-ctx.Done():
+		m.leave(l)
+		return nil, ctx.Err()
+	}
+}
+
+// Leader returns the value of the named election's leader.
+func (m *Memory) Leader(ctx context.Context, name string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.elections[name]) == 0 {
+		return "", ErrNoLeader
+	}
+	return m.elections[name][0].value, nil
+}
+
+// Expire ends the current leader's lease in the named election, as if its
+// process had stopped renewing it, and elects the next candidate.
+func (m *Memory) Expire(name string) {
+	m.mu.Lock()
+	var l *memoryLeadership
+	if len(m.elections[name]) > 0 {
+		l = m.elections[name][0]
+	}
+	m.mu.Unlock()
+	if l != nil {
+		m.leave(l)
+	}
+}
+
+// leave removes a candidacy, handing leadership on if it led.
+func (m *Memory) leave(l *memoryLeadership) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cands := m.elections[l.name]
+	for i, c := range cands {
+		if c != l {
+			continue
+		}
+		cands = append(cands[:i:i], cands[i+1:]...)
+		close(l.done)
+		if i == 0 && len(cands) > 0 {
+			close(cands[0].elected)
+		}
+		break
+	}
+	if len(cands) == 0 {
+		delete(m.elections, l.name)
+	} else {
+		m.elections[l.name] = cands
+	}
+}
+
+// Done is closed when leadership is lost.
+func (l *memoryLeadership) Done() <-chan struct{} {
+	return l.done
+}
+
+// Resign gives up leadership so the next candidate takes over.
+func (l *memoryLeadership) Resign(ctx context.Context) error {
+	l.m.leave(l)
+	return nil
+}
@@ -4,15 +4,23 @@ package metadata
 import (
 	"context"
 	"encoding/json"
-	"strings"
+	"errors"
+	"log"
+	"sync"
 	"time"
 
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
 )
 
-// Client wraps an etcd connection and provides watch hooks.
+// Client is the etcd implementation of Store.
 type Client struct {
+	watchers
 	etcd *clientv3.Client
+
+	mu      sync.Mutex
+	watches []*watchState
 }
 
 // NewClient connects to etcd. Accepts a comma-separated list of endpoints.
@@ -25,43 +33,415 @@ func NewClient(endpoints []string) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Client{etcd: cli}, nil
+	c := &Client{etcd: cli}
+	c.watchers = watchers{watch: c.watch}
+	return c, nil
 }
 
-// WatchRingConfig watches "/ring/config" and calls updateFn each time it changes.
-func (c *Client) WatchRingConfig(updateFn func(config []byte)) {
-	go func() {
-		rch := c.etcd.Watch(context.Background(), "/ring/config")
-		for wr := range rch {
-			for _, ev := range wr.Events {
-				updateFn(ev.Kv.Value)
-			}
+// Close stops the watches and disconnects from etcd.
+func (c *Client) Close() error {
+	return c.etcd.Close()
+}
+
+// watch delivers the current state of key, or of every key under it if
+// prefix is set, and then every change. The initial state is read at a
+// revision N and the watch starts at N+1, so no change is missed. A
+// broken watch resumes after the last revision delivered; if that revision
+// was compacted away, the state is listed again and the difference is
+// delivered, including deletes.
+func (c *Client) watch(key string, prefix bool, fn func(event)) {
+	w := &watchState{key: key, prefix: prefix, fn: fn, known: make(map[string]int64)}
+	c.mu.Lock()
+	c.watches = append(c.watches, w)
+	c.mu.Unlock()
+	go c.run(w)
+}
+
+// watchState is one watch's position and health.
+type watchState struct {
+	key    string
+	prefix bool
+	fn     func(event)
+	known  map[string]int64 // delivered keys and their mod revisions
+
+	// guarded by Client.mu
+	status WatchStatus
+}
+
+const (
+	// watchRetryMin and watchRetryMax bound the backoff between attempts
+	// to re-establish a broken watch.
+	watchRetryMin = 500 * time.Millisecond
+	watchRetryMax = 10 * time.Second
+)
+
+// run keeps w alive until the client is closed.
+func (c *Client) run(w *watchState) {
+	var rev int64 // last revision delivered; 0 means list first
+	backoff := watchRetryMin
+	for {
+		var err error
+		if rev == 0 {
+			rev, err = c.list(w)
+		}
+		if err == nil {
+			c.setHealth(w, rev, nil)
+			backoff = watchRetryMin
+			rev, err = c.follow(w, rev)
+		}
+		if c.etcd.Ctx().Err() != nil {
+			return // closed
+		}
+		if errors.Is(err, rpctypes.ErrCompacted) {
+			rev = 0
 		}
-	}()
+		c.setHealth(w, rev, err)
+		log.Printf("metadata: watch %s: %v; retrying in %s", w.key, err, backoff)
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > watchRetryMax {
+			backoff = watchRetryMax
+		}
+	}
 }
 
-// WatchReplicas watches "/replicas/" prefix and calls updateFn(key, value) on each change.
-func (c *Client) WatchReplicas(updateFn func(key string, value []byte)) {
-	go func() {
-		rch := c.etcd.Watch(context.Background(), "/replicas/", clientv3.WithPrefix())
-		for wr := range rch {
-			for _, ev := range wr.Events {
-				// key is the part after "/replicas/"
-				k := strings.TrimPrefix(string(ev.Kv.Key), "/replicas/")
-				updateFn(k, ev.Kv.Value)
+// list reads the watched keys at the latest revision and delivers what
+// changed since the last delivery. It returns the revision read.
+func (c *Client) list(w *watchState) (int64, error) {
+	var opts []clientv3.OpOption
+	if w.prefix {
+		opts = append(opts, clientv3.WithPrefix())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := c.etcd.Get(ctx, w.key, opts...)
+	if err != nil {
+		return 0, err
+	}
+	present := make(map[string]bool, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		k := string(kv.Key)
+		present[k] = true
+		if w.known[k] != kv.ModRevision {
+			w.known[k] = kv.ModRevision
+			w.fn(event{key: k, value: kv.Value})
+		}
+	}
+	for k := range w.known {
+		if !present[k] {
+			delete(w.known, k)
+			w.fn(event{key: k, deleted: true})
+		}
+	}
+	return resp.Header.Revision, nil
+}
+
+// follow delivers changes after revision rev until the watch breaks. It
+// returns the last revision delivered and why the watch ended.
+func (c *Client) follow(w *watchState, rev int64) (int64, error) {
+	opts := []clientv3.OpOption{clientv3.WithRev(rev + 1), clientv3.WithProgressNotify()}
+	if w.prefix {
+		opts = append(opts, clientv3.WithPrefix())
+	}
+	ctx, cancel := context.WithCancel(clientv3.WithRequireLeader(context.Background()))
+	defer cancel()
+	for wr := range c.etcd.Watch(ctx, w.key, opts...) {
+		if err := wr.Err(); err != nil {
+			return rev, err
+		}
+		for _, ev := range wr.Events {
+			k := string(ev.Kv.Key)
+			deleted := ev.Type == clientv3.EventTypeDelete
+			if deleted {
+				delete(w.known, k)
+			} else {
+				w.known[k] = ev.Kv.ModRevision
 			}
+			w.fn(event{key: k, value: ev.Kv.Value, deleted: deleted})
+			rev = ev.Kv.ModRevision
 		}
-	}()
+		if wr.IsProgressNotify() && wr.Header.Revision > rev {
+			rev = wr.Header.Revision
+		}
+		c.setHealth(w, rev, nil)
+	}
+	return rev, errWatchClosed
 }
 
-// SetReplicas writes the replica list for a specific key into etcd.
-func (c *Client) SetReplicas(key string, replicas []string) error {
+var errWatchClosed = errors.New("watch channel closed")
+
+func (c *Client) setHealth(w *watchState, rev int64, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	healthy := err == nil
+	if w.status.Since.IsZero() || w.status.Healthy != healthy {
+		w.status.Since = time.Now()
+	}
+	w.status.Key = w.key
+	w.status.Healthy = healthy
+	w.status.Revision = rev
+	w.status.Err = ""
+	if err != nil {
+		w.status.Err = err.Error()
+	}
+}
+
+// Watches reports the health of every watch.
+func (c *Client) Watches() []WatchStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]WatchStatus, len(c.watches))
+	for i, w := range c.watches {
+		out[i] = w.status
+		out[i].Key = w.key
+	}
+	return out
+}
+
+// Replicas returns the override of key and its revision.
+func (c *Client) Replicas(key string) ([]string, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	resp, err := c.etcd.Get(ctx, "/replicas/"+key)
+	if err != nil || len(resp.Kvs) == 0 {
+		return nil, 0, err
+	}
+	var replicas []string
+	if err := json.Unmarshal(resp.Kvs[0].Value, &replicas); err != nil {
+		return nil, 0, err
+	}
+	return replicas, resp.Kvs[0].ModRevision, nil
+}
+
+// SetReplicas writes the replica list for key if the override's
+// ModRevision is still rev, or unconditionally for AnyRevision.
+func (c *Client) SetReplicas(key string, replicas []string, rev int64) (int64, error) {
 	buf, err := json.Marshal(replicas)
 	if err != nil {
-		return err
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	put := clientv3.OpPut("/replicas/"+key, string(buf))
+	txn := c.etcd.Txn(ctx)
+	if rev != AnyRevision {
+		txn = txn.If(clientv3.Compare(clientv3.ModRevision("/replicas/"+key), "=", rev))
+	}
+	resp, err := txn.Then(put).Commit()
+	if err != nil {
+		return 0, err
+	}
+	if !resp.Succeeded {
+		return 0, ErrConflict
+	}
+	return resp.Header.Revision, nil
+}
+
+// ClearReplicas deletes the replica override of key.
+func (c *Client) ClearReplicas(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := c.etcd.Delete(ctx, "/replicas/"+key)
+	return err
+}
+
+// ClearReplicasWithPrefix deletes the replica overrides of every key with
+// prefix and returns how many there were.
+func (c *Client) ClearReplicasWithPrefix(prefix string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	resp, err := c.etcd.Delete(ctx, "/replicas/"+prefix, clientv3.WithPrefix())
+	if err != nil {
+		return 0, err
 	}
+	return int(resp.Deleted), nil
+}
+
+// SetPolicy stores a JSON-encoded placement policy under "/policies/<name>".
+func (c *Client) SetPolicy(name string, policy []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := c.etcd.Put(ctx, "/policies/"+name, string(policy))
+	return err
+}
+
+// DeletePolicy removes the placement policy stored under name.
+func (c *Client) DeletePolicy(name string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
-	_, err = c.etcd.Put(ctx, "/replicas/"+key, string(buf))
+	_, err := c.etcd.Delete(ctx, "/policies/"+name)
+	return err
+}
+
+// SetResidencyRule stores a JSON-encoded residency rule under "/residency/<name>".
+func (c *Client) SetResidencyRule(name string, rule []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := c.etcd.Put(ctx, "/residency/"+name, string(rule))
+	return err
+}
+
+// DeleteResidencyRule removes the residency rule stored under name.
+func (c *Client) DeleteResidencyRule(name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := c.etcd.Delete(ctx, "/residency/"+name)
+	return err
+}
+
+// RingConfig returns "/ring/config" and its ModRevision.
+func (c *Client) RingConfig() ([]byte, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	resp, err := c.etcd.Get(ctx, "/ring/config")
+	if err != nil || len(resp.Kvs) == 0 {
+		return nil, 0, err
+	}
+	return resp.Kvs[0].Value, resp.Kvs[0].ModRevision, nil
+}
+
+// SetRingConfig publishes a new "/ring/config" with the next epoch if its
+// ModRevision is still rev. For AnyRevision it retries until the epoch it
+// read is the one it replaces. A matching proposal is removed in the same
+// transaction; a newer one is left for the next round.
+func (c *Client) SetRingConfig(config []byte, rev int64) (int64, error) {
+	for {
+		prev, cur, err := c.RingConfig()
+		if err != nil {
+			return 0, err
+		}
+		if rev != AnyRevision && cur != rev {
+			return 0, ErrConflict
+		}
+		stamped, err := withEpoch(config, prev)
+		if err != nil {
+			return 0, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		resp, err := c.etcd.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision("/ring/config"), "=", cur)).
+			Then(
+				clientv3.OpPut("/ring/config", string(stamped)),
+				clientv3.OpTxn(
+					[]clientv3.Cmp{clientv3.Compare(clientv3.Value("/ring/proposed"), "=", string(config))},
+					[]clientv3.Op{clientv3.OpDelete("/ring/proposed")},
+					nil,
+				),
+			).
+			Commit()
+		cancel()
+		if err != nil {
+			return 0, err
+		}
+		if resp.Succeeded {
+			return resp.Header.Revision, nil
+		}
+		if rev != AnyRevision {
+			return 0, ErrConflict
+		}
+	}
+}
+
+// ProposeRingConfig stages config under "/ring/proposed".
+func (c *Client) ProposeRingConfig(config []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err := c.etcd.Put(ctx, "/ring/proposed", string(config))
+	return err
+}
+
+// registrationTTL is the lease TTL in seconds of a node registration: a
+// node that stops renewing disappears from /nodes/ after at most this long.
+const registrationTTL = 10
+
+// etcdLease is a node registration kept alive by an etcd session.
+type etcdLease struct {
+	etcd    *clientv3.Client
+	session *concurrency.Session
+}
+
+// Register puts node under "/nodes/<id>" with a lease the session keeps
+// alive in the background.
+func (c *Client) Register(ctx context.Context, node NodeInfo) (Lease, error) {
+	buf, err := json.Marshal(node)
+	if err != nil {
+		return nil, err
+	}
+	session, err := concurrency.NewSession(c.etcd, concurrency.WithTTL(registrationTTL), concurrency.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.etcd.Put(ctx, "/nodes/"+node.ID, string(buf), clientv3.WithLease(session.Lease())); err != nil {
+		session.Close()
+		return nil, err
+	}
+	return &etcdLease{etcd: c.etcd, session: session}, nil
+}
+
+// Done is closed when the lease expired or was closed.
+func (l *etcdLease) Done() <-chan struct{} {
+	return l.session.Done()
+}
+
+// Close revokes the lease with ctx, deleting the registration. The session
+// is bound to the context Register was called with, which is usually done
+// by the time the registration is closed, so it cannot revoke the lease
+// itself.
+func (l *etcdLease) Close(ctx context.Context) error {
+	l.session.Orphan()
+	_, err := l.etcd.Revoke(ctx, l.session.Lease())
+	return err
+}
+
+// electionTTL is the session TTL in seconds backing an etcd Leadership: a leader
+// that stops renewing is replaced after at most this long.
+const electionTTL = 10
+
+// etcdLeadership is held by the winner of Campaign until it resigns or its
+// etcd session expires.
+type etcdLeadership struct {
+	session  *concurrency.Session
+	election *concurrency.Election
+}
+
+// Campaign blocks until this process leads the election "/election/<name>"
+// or ctx is done. value is published as the leader's identity, e.g. its
+// address, and can be read back with Leader.
+func (c *Client) Campaign(ctx context.Context, name, value string) (Leadership, error) {
+	session, err := concurrency.NewSession(c.etcd, concurrency.WithTTL(electionTTL))
+	if err != nil {
+		return nil, err
+	}
+	election := concurrency.NewElection(session, "/election/"+name)
+	if err := election.Campaign(ctx, value); err != nil {
+		session.Close()
+		return nil, err
+	}
+	return &etcdLeadership{session: session, election: election}, nil
+}
+
+// Leader returns the value published by the current leader of an election.
+// The leader is the candidate with the oldest key under the election prefix.
+func (c *Client) Leader(ctx context.Context, name string) (string, error) {
+	resp, err := c.etcd.Get(ctx, "/election/"+name+"/", clientv3.WithFirstCreate()...)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Kvs) == 0 {
+		return "", ErrNoLeader
+	}
+	return string(resp.Kvs[0].Value), nil
+}
+
+// Done is closed when leadership is lost because the session expired.
+func (l *etcdLeadership) Done() <-chan struct{} {
+	return l.session.Done()
+}
+
+// Resign gives up leadership so a standby can take over immediately.
+func (l *etcdLeadership) Resign(ctx context.Context) error {
+	err := l.election.Resign(ctx)
+	if cerr := l.session.Close(); err == nil {
+		err = cerr
+	}
 	return err
 }
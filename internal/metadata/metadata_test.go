@@ -0,0 +1,75 @@
+// internal/metadata/metadata_test.go
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+func TestWatchResumesAfterCompaction(t *testing.T) {
+	c := startEtcd(t)
+	c.SetPolicy("a", []byte(`1`))
+	c.SetPolicy("b", []byte(`2`))
+
+	var got []string
+	w := &watchState{key: "/policies/", prefix: true, known: make(map[string]int64), fn: func(ev event) {
+		got = append(got, fmt.Sprintf("%s=%s", ev.key, ev.value))
+	}}
+	rev, err := c.list(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != "[/policies/a=1 /policies/b=2]" {
+		t.Fatalf("initial list delivered %v", got)
+	}
+
+	// while the watch is down, its keys change and the history is compacted
+	c.SetPolicy("c", []byte(`3`))
+	c.DeletePolicy("a")
+	resp, err := c.etcd.Get(context.Background(), "/policies/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.etcd.Compact(context.Background(), resp.Header.Revision); err != nil {
+		t.Fatal(err)
+	}
+
+	got = nil
+	if _, err := c.follow(w, rev); !errors.Is(err, rpctypes.ErrCompacted) {
+		t.Fatalf("resuming at a compacted revision = %v, want ErrCompacted", err)
+	}
+	// run lists again, delivering the difference, deletes included
+	if _, err := c.list(w); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	if fmt.Sprint(got) != "[/policies/a= /policies/c=3]" {
+		t.Fatalf("relisting delivered %v, want a deleted and c added", got)
+	}
+}
+
+func TestCloseRegistrationAfterItsContext(t *testing.T) {
+	c := startEtcd(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	lease, err := c.Register(ctx, NodeInfo{ID: "n1", Addr: "n1:9000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// as cmd/server does: the registration ends with the process's signal
+	cancel()
+	if err := lease.Close(context.Background()); err != nil {
+		t.Fatalf("Close after the registration context ended: %v", err)
+	}
+	resp, err := c.etcd.Get(context.Background(), "/nodes/n1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Kvs) != 0 {
+		t.Fatal("registration left in place until its TTL")
+	}
+}
@@ -0,0 +1,227 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// Store is the cluster metadata the proxy, the placement manager and the
+// rebalancer share. Client implements it on etcd and Memory in process;
+// both deliver watch events the same way, so components and tests can use
+// either.
+//
+// Every Watch method first delivers the current state of its keys and then
+// each change, in order, on its own goroutine.
+type Store interface {
+	// WatchRingConfig calls updateFn with every new "/ring/config".
+	// Deleting the configuration is not passed on: routing keeps the last
+	// one.
+	WatchRingConfig(updateFn func(config []byte))
+	// WatchProposedRingConfig calls updateFn with every configuration staged
+	// under "/ring/proposed".
+	WatchProposedRingConfig(updateFn func(config []byte))
+	// WatchReplicas calls updateFn(key, value) on every change to a
+	// "/replicas/<key>" override; value is empty when it is deleted.
+	WatchReplicas(updateFn func(key string, value []byte))
+	// WatchPolicies and WatchResidency call updateFn(name, value) on every
+	// change to a placement policy or residency rule; value is empty when
+	// it is deleted.
+	WatchPolicies(updateFn func(name string, value []byte))
+	WatchResidency(updateFn func(name string, value []byte))
+	// WatchNodes calls updateFn(id, value) on every change to a node's
+	// registration; value is empty when the node is gone.
+	WatchNodes(updateFn func(id string, value []byte))
+
+	// RingConfig returns the current "/ring/config" and its revision, 0 if
+	// there is none.
+	RingConfig() ([]byte, int64, error)
+	// SetRingConfig publishes config as "/ring/config" if its revision is
+	// still rev, stamping it with the epoch after the one it replaces, and
+	// returns the new revision. A staged proposal equal to config is
+	// removed in the same transaction.
+	SetRingConfig(config []byte, rev int64) (int64, error)
+	// ProposeRingConfig stages a configuration under "/ring/proposed" for
+	// the rebalancer to apply once the data has moved.
+	ProposeRingConfig(config []byte) error
+	// Replicas returns the override of key and its revision; both are
+	// zero if there is none. SetReplicas replaces the override if its
+	// revision is still rev (0: if there is none) and returns the new
+	// revision.
+	Replicas(key string) ([]string, int64, error)
+	SetReplicas(key string, replicas []string, rev int64) (int64, error)
+	// ClearReplicas deletes the override of key, returning it to its
+	// default ring placement. ClearReplicasWithPrefix does so for every key
+	// with the prefix, all keys if it is empty, and returns how many
+	// overrides it deleted.
+	ClearReplicas(key string) error
+	ClearReplicasWithPrefix(prefix string) (int, error)
+	SetPolicy(name string, policy []byte) error
+	DeletePolicy(name string) error
+	SetResidencyRule(name string, rule []byte) error
+	DeleteResidencyRule(name string) error
+
+	// Campaign blocks until the caller leads the named election or ctx is
+	// done; the leadership is a lease that lasts until it is resigned or
+	// expires. Leader returns the current leader's value.
+	Campaign(ctx context.Context, name, value string) (Leadership, error)
+	Leader(ctx context.Context, name string) (string, error)
+
+	// Register publishes node under "/nodes/<id>" for as long as the
+	// returned lease is kept alive, at most until ctx is done. Closing the
+	// lease deletes the registration even after ctx is done.
+	Register(ctx context.Context, node NodeInfo) (Lease, error)
+
+	// Watches reports the health of every watch started so far.
+	Watches() []WatchStatus
+}
+
+// WatchStatus is the health of one watch. An unhealthy watch is retrying;
+// until it recovers, its callback may be missing recent changes.
+type WatchStatus struct {
+	Key      string    `json:"key"`
+	Healthy  bool      `json:"healthy"`
+	Revision int64     `json:"revision"` // last revision delivered
+	Err      string    `json:"error,omitempty"`
+	Since    time.Time `json:"since"` // when Healthy last changed
+}
+
+// NodeInfo is what a storage server advertises under "/nodes/<id>".
+type NodeInfo struct {
+	ID     string `json:"id"`
+	Addr   string `json:"addr"` // gRPC address, used as the ring node ID
+	Region string `json:"region"`
+	Zone   string `json:"zone,omitempty"`
+	// Capacity is the node's relative storage capacity.
+	Capacity float64 `json:"capacity,omitempty"`
+}
+
+// Lease keeps a registration alive until it is closed or expires.
+type Lease interface {
+	// Done is closed when the lease expired or was closed.
+	Done() <-chan struct{}
+	// Close revokes the lease, removing what it kept alive at once.
+	Close(ctx context.Context) error
+}
+
+// Leadership is held by the winner of Campaign.
+type Leadership interface {
+	// Done is closed when leadership is lost.
+	Done() <-chan struct{}
+	// Resign gives up leadership so a standby can take over immediately.
+	Resign(ctx context.Context) error
+}
+
+// AnyRevision makes a conditional write unconditional, for operators who
+// mean to overwrite whatever is there.
+const AnyRevision int64 = -1
+
+// ErrConflict is returned by a conditional write when the key changed since
+// the revision it was given.
+var ErrConflict = errors.New("metadata: key changed concurrently")
+
+// ErrNoLeader is returned by Leader when an election has no candidates.
+var ErrNoLeader = concurrency.ErrElectionNoLeader
+
+var (
+	_ Store = (*Client)(nil)
+	_ Store = (*Memory)(nil)
+)
+
+// event is a change to one metadata key.
+type event struct {
+	key     string
+	value   []byte
+	deleted bool
+}
+
+// watchers implements the Watch methods of Store on top of a backend's
+// raw watch, so every backend interprets events identically.
+type watchers struct {
+	watch func(key string, prefix bool, fn func(event))
+}
+
+// WatchRingConfig watches "/ring/config" and calls updateFn each time it
+// is set. Deletes are skipped, so routing keeps the last configuration.
+func (w watchers) WatchRingConfig(updateFn func(config []byte)) {
+	w.watch("/ring/config", false, func(ev event) {
+		if !ev.deleted {
+			updateFn(ev.value)
+		}
+	})
+}
+
+// WatchReplicas watches "/replicas/" prefix and calls updateFn(key, value) on
+// each change; value is nil when the override is deleted.
+func (w watchers) WatchReplicas(updateFn func(key string, value []byte)) {
+	// key is the part after "/replicas/"
+	w.watchNamed("/replicas/", updateFn)
+}
+
+// WatchPolicies watches the placement policies under "/policies/" and calls
+// updateFn(name, value) on each change; value is empty when a policy is deleted.
+func (w watchers) WatchPolicies(updateFn func(name string, value []byte)) {
+	w.watchNamed("/policies/", updateFn)
+}
+
+// WatchResidency watches the residency rules under "/residency/" and calls
+// updateFn(name, value) on each change; value is empty when a rule is deleted.
+func (w watchers) WatchResidency(updateFn func(name string, value []byte)) {
+	w.watchNamed("/residency/", updateFn)
+}
+
+// WatchNodes watches the registrations under "/nodes/", passing nil when a
+// node's lease ends.
+func (w watchers) WatchNodes(updateFn func(id string, value []byte)) {
+	w.watchNamed("/nodes/", updateFn)
+}
+
+// WatchProposedRingConfig watches "/ring/proposed", where operators stage a
+// new ring configuration for the rebalancer to apply.
+func (w watchers) WatchProposedRingConfig(updateFn func(config []byte)) {
+	w.watch("/ring/proposed", false, func(ev event) {
+		if !ev.deleted {
+			updateFn(ev.value)
+		}
+	})
+}
+
+// watchNamed watches the documents under prefix, passing nil for deletes.
+func (w watchers) watchNamed(prefix string, updateFn func(name string, value []byte)) {
+	w.watch(prefix, true, func(ev event) {
+		name := strings.TrimPrefix(ev.key, prefix)
+		if ev.deleted {
+			updateFn(name, nil)
+			return
+		}
+		updateFn(name, ev.value)
+	})
+}
+
+// withEpoch returns config with its "epoch" field set to one past that of
+// prev, the configuration it replaces. Every published ring configuration
+// thus has a higher epoch than the last, which proxies and servers compare
+// to detect requests routed with an outdated ring.
+func withEpoch(config, prev []byte) ([]byte, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(config, &doc); err != nil {
+		return nil, err
+	}
+	var old struct {
+		Epoch uint64 `json:"epoch"`
+	}
+	if len(prev) > 0 {
+		// an unparsable predecessor restarts the count
+		json.Unmarshal(prev, &old)
+	}
+	epoch, err := json.Marshal(old.Epoch + 1)
+	if err != nil {
+		return nil, err
+	}
+	doc["epoch"] = epoch
+	return json.Marshal(doc)
+}
@@ -0,0 +1,282 @@
+// internal/metadata/store_test.go
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// startEtcd runs a single-member etcd in a temporary directory and returns
+// a Client connected to it.
+func startEtcd(t *testing.T) *Client {
+	t.Helper()
+	cfg := embed.NewConfig()
+	cfg.Name = "test"
+	cfg.Dir = t.TempDir()
+	cfg.LogLevel = "fatal"
+	client := url.URL{Scheme: "http", Host: freeAddr(t)}
+	peer := url.URL{Scheme: "http", Host: freeAddr(t)}
+	cfg.ListenClientUrls, cfg.AdvertiseClientUrls = []url.URL{client}, []url.URL{client}
+	cfg.ListenPeerUrls, cfg.AdvertisePeerUrls = []url.URL{peer}, []url.URL{peer}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		t.Fatalf("start etcd: %v", err)
+	}
+	t.Cleanup(e.Close)
+	select {
+	case <-e.Server.ReadyNotify():
+	case err := <-e.Err():
+		t.Fatalf("etcd: %v", err)
+	case <-time.After(30 * time.Second):
+		t.Fatal("etcd not ready after 30s")
+	}
+	c, err := NewClient([]string{client.Host})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	return lis.Addr().String()
+}
+
+// forEachStore runs test against a Memory store and an etcd-backed Client,
+// which must behave the same.
+func forEachStore(t *testing.T, test func(t *testing.T, s Store)) {
+	t.Run("memory", func(t *testing.T) { test(t, NewMemory()) })
+	t.Run("etcd", func(t *testing.T) { test(t, startEtcd(t)) })
+}
+
+// collector gathers watch callbacks for assertions.
+type collector chan string
+
+func (c collector) named(name string, value []byte) {
+	c <- fmt.Sprintf("%s=%s", name, value)
+}
+
+// expect waits for the next callbacks to be want, in order.
+func (c collector) expect(t *testing.T, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		select {
+		case got := <-c:
+			if got != w {
+				t.Fatalf("watch delivered %q, want %q", got, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("watch did not deliver %q", w)
+		}
+	}
+}
+
+func TestStoreReplicas(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		if repls, rev, err := s.Replicas("k"); err != nil || repls != nil || rev != 0 {
+			t.Fatalf("Replicas of a missing override = %v, %d, %v", repls, rev, err)
+		}
+		// revision 0 creates the override only if there is none
+		rev, err := s.SetReplicas("k", []string{"a", "b"}, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repls, got, err := s.Replicas("k")
+		if err != nil || got != rev || fmt.Sprint(repls) != "[a b]" {
+			t.Fatalf("Replicas = %v, %d, %v; want [a b] at %d", repls, got, err, rev)
+		}
+		if _, err := s.SetReplicas("k", []string{"c"}, 0); !errors.Is(err, ErrConflict) {
+			t.Fatalf("stale SetReplicas = %v, want ErrConflict", err)
+		}
+		if _, err := s.SetReplicas("k", []string{"c"}, rev); err != nil {
+			t.Fatalf("SetReplicas at the current revision: %v", err)
+		}
+		if _, err := s.SetReplicas("k", []string{"d"}, AnyRevision); err != nil {
+			t.Fatalf("unconditional SetReplicas: %v", err)
+		}
+
+		for _, key := range []string{"p/1", "p/2", "q/1"} {
+			if _, err := s.SetReplicas(key, []string{"a"}, AnyRevision); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if n, err := s.ClearReplicasWithPrefix("p/"); err != nil || n != 2 {
+			t.Fatalf("ClearReplicasWithPrefix = %d, %v; want 2", n, err)
+		}
+		if err := s.ClearReplicas("k"); err != nil {
+			t.Fatal(err)
+		}
+		for key, want := range map[string]bool{"p/1": false, "p/2": false, "q/1": true, "k": false} {
+			if repls, _, _ := s.Replicas(key); (repls != nil) != want {
+				t.Fatalf("override of %s = %v after clearing", key, repls)
+			}
+		}
+	})
+}
+
+func TestStoreRingConfig(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		if cfg, rev, err := s.RingConfig(); err != nil || cfg != nil || rev != 0 {
+			t.Fatalf("RingConfig of an empty store = %s, %d, %v", cfg, rev, err)
+		}
+		proposals := make(chan string, 10)
+		s.WatchProposedRingConfig(func(config []byte) { proposals <- string(config) })
+		first := []byte(`{"nodes":["a"]}`)
+		if err := s.ProposeRingConfig(first); err != nil {
+			t.Fatal(err)
+		}
+		collector(proposals).expect(t, string(first))
+
+		rev, err := s.SetRingConfig(first, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.SetRingConfig([]byte(`{"nodes":["b"]}`), 0); !errors.Is(err, ErrConflict) {
+			t.Fatalf("stale SetRingConfig = %v, want ErrConflict", err)
+		}
+		if _, err := s.SetRingConfig([]byte(`{"nodes":["a","b"]}`), AnyRevision); err != nil {
+			t.Fatal(err)
+		}
+		raw, cur, err := s.RingConfig()
+		if err != nil || cur <= rev {
+			t.Fatalf("RingConfig = %s at %d, %v; want a revision after %d", raw, cur, err, rev)
+		}
+		var cfg struct {
+			Nodes []string `json:"nodes"`
+			Epoch uint64   `json:"epoch"`
+		}
+		if err := json.Unmarshal(raw, &cfg); err != nil || cfg.Epoch != 2 || len(cfg.Nodes) != 2 {
+			t.Fatalf("RingConfig = %s, want epoch 2 with nodes a and b", raw)
+		}
+
+		// the published proposal was removed; watching again finds nothing
+		again := make(chan string, 10)
+		s.WatchProposedRingConfig(func(config []byte) { again <- string(config) })
+		select {
+		case p := <-again:
+			t.Fatalf("proposal %s still staged after it was published", p)
+		case <-time.After(200 * time.Millisecond):
+		}
+	})
+}
+
+func TestStoreWatches(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		if err := s.SetPolicy("b", []byte(`2`)); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetPolicy("a", []byte(`1`)); err != nil {
+			t.Fatal(err)
+		}
+		policies := make(collector, 10)
+		s.WatchPolicies(policies.named)
+		// the current state first, in key order
+		policies.expect(t, "a=1", "b=2")
+
+		s.SetPolicy("c", []byte(`3`))
+		s.DeletePolicy("a")
+		s.SetPolicy("b", []byte(`4`))
+		policies.expect(t, "c=3", "a=", "b=4")
+
+		residency := make(collector, 10)
+		s.WatchResidency(residency.named)
+		s.SetResidencyRule("eu", []byte(`{}`))
+		s.DeleteResidencyRule("eu")
+		residency.expect(t, "eu={}", "eu=")
+
+		replicas := make(collector, 10)
+		s.WatchReplicas(replicas.named)
+		s.SetReplicas("k", []string{"a"}, AnyRevision)
+		s.ClearReplicas("k")
+		replicas.expect(t, `k=["a"]`, "k=")
+
+		time.Sleep(100 * time.Millisecond)
+		for _, w := range s.Watches() {
+			if !w.Healthy {
+				t.Fatalf("watch %+v unhealthy", w)
+			}
+		}
+	})
+}
+
+func TestStoreElection(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+		if _, err := s.Leader(ctx, "mgr"); !errors.Is(err, ErrNoLeader) {
+			t.Fatalf("Leader of an empty election = %v, want ErrNoLeader", err)
+		}
+		first, err := s.Campaign(ctx, "mgr", "a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if leader, err := s.Leader(ctx, "mgr"); err != nil || leader != "a" {
+			t.Fatalf("Leader = %q, %v; want a", leader, err)
+		}
+
+		elected := make(chan Leadership, 1)
+		go func() {
+			l, err := s.Campaign(ctx, "mgr", "b")
+			if err != nil {
+				t.Error(err)
+			}
+			elected <- l
+		}()
+		select {
+		case <-elected:
+			t.Fatal("second candidate elected while the first leads")
+		case <-time.After(200 * time.Millisecond):
+		}
+		if err := first.Resign(ctx); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-first.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatal("resigned leadership not done")
+		}
+		second := <-elected
+		if leader, err := s.Leader(ctx, "mgr"); err != nil || leader != "b" {
+			t.Fatalf("Leader after resigning = %q, %v; want b", leader, err)
+		}
+		second.Resign(ctx)
+	})
+}
+
+func TestStoreRegister(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		nodes := make(collector, 10)
+		s.WatchNodes(nodes.named)
+		ctx := context.Background()
+		lease, err := s.Register(ctx, NodeInfo{ID: "n1", Addr: "n1:9000", Region: "eu"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes.expect(t, `n1={"id":"n1","addr":"n1:9000","region":"eu"}`)
+		if err := lease.Close(ctx); err != nil {
+			t.Fatal(err)
+		}
+		nodes.expect(t, "n1=")
+		select {
+		case <-lease.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatal("closed lease not done")
+		}
+	})
+}
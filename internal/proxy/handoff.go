@@ -0,0 +1,136 @@
+// internal/proxy/handoff.go
+package proxy
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/replication"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/proto"
+)
+
+// hintKey identifies a write a substitute holds for a replica.
+type hintKey struct {
+	key      string
+	intended string
+}
+
+// hint records that holder accepted a write of key at version on behalf of
+// the replica that was down. Hints live in the proxy's memory: if the proxy
+// restarts before handing them off, the rebalancer's next copy of the key
+// repairs the replica.
+type hint struct {
+	holder  string
+	version uint64
+}
+
+// hint remembers a substitute write, keeping the newest per key and replica.
+func (s *Server) hint(key, holder, intended string, version uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := hintKey{key: key, intended: intended}
+	if h, ok := s.hints[k]; ok && h.version > version {
+		return
+	}
+	s.hints[k] = hint{holder: holder, version: version}
+}
+
+// dropHints forgets the hinted writes of key and returns their holders.
+func (s *Server) dropHints(key string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var holders []string
+	for k, h := range s.hints {
+		if k.key == key {
+			delete(s.hints, k)
+			if !contains(holders, h.holder) {
+				holders = append(holders, h.holder)
+			}
+		}
+	}
+	return holders
+}
+
+// RunHandoff hands writes held by substitutes back to their replicas every
+// interval until ctx is done.
+func (s *Server) RunHandoff(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.handoff(ctx)
+		}
+	}
+}
+
+// handoff copies every hinted key whose replica is healthy again from its
+// holder, then drops the substitute copy unless the holder has since become
+// a replica itself. Hints for nodes that left the ring are dropped.
+func (s *Server) handoff(ctx context.Context) {
+	nodes := s.ring.AllNodes()
+	s.mu.Lock()
+	pending := make(map[hintKey]hint, len(s.hints))
+	for k, h := range s.hints {
+		if !contains(nodes, k.intended) {
+			delete(s.hints, k)
+			continue
+		}
+		if s.Health.Healthy(k.intended) {
+			pending[k] = h
+		}
+	}
+	s.mu.Unlock()
+
+	for k, h := range pending {
+		req := &proto.TransferRequest{Keys: []string{k.key}}
+		if _, err := replication.Transfer(ctx, h.holder, k.intended, req); err != nil {
+			log.Printf("handoff of %q from %s to %s: %v", k.key, h.holder, k.intended, err)
+			continue
+		}
+		if !contains(s.ring.GetReplicaList(k.key, s.R), h.holder) {
+			if err := s.drop(ctx, h.holder, k.key, h.version); err != nil {
+				log.Printf("handoff of %q: drop from %s: %v", k.key, h.holder, err)
+			}
+		}
+		s.mu.Lock()
+		if cur, ok := s.hints[k]; ok && cur == h {
+			delete(s.hints, k)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// drop deletes key from addr unless it holds a version newer than version.
+func (s *Server) drop(ctx context.Context, addr, key string, version uint64) error {
+	conn, err := s.dial(ctx, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = proto.NewKVClient(conn).Delete(ctx, &proto.DeleteRequest{Key: key, Version: version})
+	return err
+}
+
+// minus returns the elements of a not in b.
+func minus(a, b []string) []string {
+	var out []string
+	for _, v := range a {
+		if !contains(b, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func contains(slice []string, s string) bool {
+	for _, v := range slice {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
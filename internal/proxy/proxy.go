@@ -4,76 +4,399 @@ package proxy
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcmd "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/audit"
 	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/health"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/kvstore"
 	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/metadata"
 	"github.com/ksharma120497/adaptive-geo-distributed-database/proto"
 )
 
+// RegionHeader is the gRPC metadata key clients use to name their region.
+const RegionHeader = "x-client-region"
+
+// AccessRecorder receives the proxy's access telemetry. replication.Manager
+// implements it in-process; telemetry.Reporter forwards to a remote manager.
+type AccessRecorder interface {
+	RecordRead(key, region string)
+	RecordWrite(key, region string)
+}
+
 // Server implements proto.KVServer.
 // Embedding UnimplementedKVServer gives you the mustEmbedUnimplementedKVServer method.
 type Server struct {
 	proto.UnimplementedKVServer
 
 	ring *hashring.Ring
-	md   *metadata.Client
+	md   metadata.Store
 	R    int
+
+	// Region is the proxy's own region, attributed to requests that do not
+	// carry RegionHeader.
+	Region string
+	// Recorder, if set, is told about every read and write.
+	Recorder AccessRecorder
+	// Health, if set, is told the outcome of every request to a storage
+	// node and decides which replicas writes replace with substitutes.
+	// Reads skip the nodes the ring's Healthy hook rejects.
+	Health *health.Tracker
+	// WriteQuorum is the number of acknowledgements a write needs; 0, or
+	// more than the key has replicas, means all of them.
+	WriteQuorum int
+	// DialOptions are added to the options the proxy dials storage nodes
+	// with, e.g. interceptors.
+	DialOptions []grpc.DialOption
+
+	mu    sync.Mutex
+	hints map[hintKey]hint // writes held by substitutes
 }
 
+// dialTimeout bounds connecting to a storage node.
+const dialTimeout = 200 * time.Millisecond
+
 // NewProxyServer constructs the proxy service.
-func NewProxyServer(r *hashring.Ring, md *metadata.Client, R int) *Server {
+func NewProxyServer(r *hashring.Ring, md metadata.Store, R int) *Server {
 	return &Server{
 		UnimplementedKVServer: proto.UnimplementedKVServer{},
 		ring:                  r,
 		md:                    md,
 		R:                     R,
+		hints:                 make(map[hintKey]hint),
 	}
 }
 
+// Put writes to every replica of the key. The proxy stamps the write with a
+// version so all replicas, and any migration copying the key, agree on which
+// write is newest.
+//
+// Writes are sloppy: a replica Health reports down, or one that fails, is
+// replaced by the next healthy node of the key's preference list, which
+// keeps a hint so the write is handed off once the replica is back. A
+// server that refuses the write because it no longer owns the key
+// redirects it to the key's owners at the server's newer epoch. A write
+// whose targets, redirect owners included, break the key's residency rules
+// fails with FailedPrecondition. The write succeeds once WriteQuorum targets
+// acknowledge it.
 func (s *Server) Put(ctx context.Context, req *proto.PutRequest) (*proto.PutReply, error) {
 	replicas := s.ring.GetReplicaList(req.Key, s.R)
 	if len(replicas) == 0 {
 		return nil, fmt.Errorf("no replicas for key %q", req.Key)
 	}
-	for _, addr := range replicas {
-		dialCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
-		conn, err := grpc.DialContext(dialCtx, addr, grpc.WithInsecure(), grpc.WithBlock())
-		cancel()
-		if err != nil {
-			return nil, fmt.Errorf("dial %s: %w", addr, err)
+	if req.Version == 0 {
+		req.Version = uint64(time.Now().UnixNano())
+	}
+	req.Epoch = s.ring.Epoch()
+	if s.Recorder != nil {
+		s.Recorder.RecordWrite(req.Key, s.clientRegion(ctx))
+	}
+
+	used := make(map[string]bool) // nodes written to, or about to be
+	var spares []string
+	for _, node := range minus(s.ring.Preference(req.Key, s.R), replicas) {
+		if s.Health.Healthy(node) {
+			spares = append(spares, node)
 		}
-		defer conn.Close()
+	}
+	// substitute returns the target standing in for the replica intended.
+	substitute := func(intended string) (writeTarget, bool) {
+		for len(spares) > 0 {
+			node := spares[0]
+			spares = spares[1:]
+			if !used[node] {
+				used[node] = true
+				return writeTarget{addr: node, intended: intended}, true
+			}
+		}
+		return writeTarget{}, false
+	}
 
-		client := proto.NewKVClient(conn)
-		if _, err := client.Put(ctx, req); err != nil {
-			return nil, fmt.Errorf("put to %s: %w", addr, err)
+	targets := make([]writeTarget, 0, len(replicas))
+	for _, node := range replicas {
+		used[node] = true
+	}
+	for _, node := range replicas {
+		t := writeTarget{addr: node}
+		if !s.Health.Healthy(node) {
+			if sub, ok := substitute(node); ok {
+				t = sub
+			}
+		}
+		targets = append(targets, t)
+	}
+
+	acks := 0
+	var firstErr error
+	for len(targets) > 0 {
+		if err := s.checkResidency(req.Key, targets); err != nil {
+			return nil, err
+		}
+		errs := s.putAll(ctx, targets, req)
+		var retry []writeTarget
+		for i, t := range targets {
+			if errs[i] == nil {
+				acks++
+				if t.intended != "" {
+					s.hint(req.Key, t.addr, t.intended, req.Version)
+				}
+				continue
+			}
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			if rd, ok := kvstore.Redirected(errs[i]); ok {
+				for _, node := range rd.Owners {
+					if !used[node] {
+						used[node] = true
+						retry = append(retry, writeTarget{addr: node})
+					}
+				}
+				continue
+			}
+			if !health.IsNodeFailure(errs[i]) {
+				continue
+			}
+			intended := t.intended
+			if intended == "" {
+				intended = t.addr
+			}
+			if sub, ok := substitute(intended); ok {
+				retry = append(retry, sub)
+			}
 		}
+		targets = retry
+	}
+	if quorum := s.writeQuorum(len(replicas)); acks < quorum {
+		if _, ok := kvstore.Redirected(firstErr); ok {
+			// routing is behind the servers; the client may retry
+			return nil, status.Errorf(codes.FailedPrecondition, "put %q: %v", req.Key, firstErr)
+		}
+		return nil, status.Errorf(codes.Unavailable, "put %q: %d of %d writes acknowledged: %v", req.Key, acks, quorum, firstErr)
+	}
+	return &proto.PutReply{Success: true, Applied: true, Version: req.Version}, nil
+}
+
+// writeTarget is a node a write goes to. intended names the replica it
+// stands in for if it is a substitute; the write tells the node so, as it
+// need not own the key.
+type writeTarget struct {
+	addr     string
+	intended string
+}
+
+// checkResidency fails a write if any of its targets may not hold key.
+// Replicas and substitutes come from the ring, which already leaves such
+// nodes out, but redirect owners come from a server's configuration.
+func (s *Server) checkResidency(key string, targets []writeTarget) error {
+	addrs := make([]string, len(targets))
+	for i, t := range targets {
+		addrs[i] = t.addr
+	}
+	if err := s.ring.CheckResidency(key, addrs); err != nil {
+		audit.Record("proxy", "residency_violation", err)
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return nil
+}
+
+// putAll writes req to every target concurrently and returns each result.
+func (s *Server) putAll(ctx context.Context, targets []writeTarget, req *proto.PutRequest) []error {
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		r := &proto.PutRequest{Key: req.Key, Value: req.Value, Version: req.Version, Epoch: req.Epoch, HintedFor: t.intended}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.put(ctx, t.addr, r)
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+// put writes req to a single node.
+func (s *Server) put(ctx context.Context, addr string, req *proto.PutRequest) error {
+	conn, err := s.dial(ctx, addr)
+	if err == nil {
+		defer conn.Close()
+		_, err = proto.NewKVClient(conn).Put(ctx, req)
+	}
+	s.Health.Observe(addr, err)
+	if err != nil {
+		return fmt.Errorf("put to %s: %w", addr, err)
+	}
+	return nil
+}
+
+// writeQuorum is the number of acknowledgements a write to n replicas
+// needs.
+func (s *Server) writeQuorum(n int) int {
+	if s.WriteQuorum <= 0 || s.WriteQuorum > n {
+		return n
 	}
-	return &proto.PutReply{Success: true}, nil
+	return s.WriteQuorum
 }
 
+// Get reads from the first replica that answers, trying healthy replicas
+// before those Health reports down and following redirects from servers
+// that no longer own the key.
 func (s *Server) Get(ctx context.Context, req *proto.GetRequest) (*proto.GetReply, error) {
-	replicas := s.ring.GetReplicaList(req.Key, s.R)
+	replicas := s.ring.ReadReplicas(req.Key, s.R)
 	if len(replicas) == 0 {
 		return nil, fmt.Errorf("no replicas for key %q", req.Key)
 	}
-	target := replicas[0]
+	req.Epoch = s.ring.Epoch()
+	if s.Recorder != nil {
+		s.Recorder.RecordRead(req.Key, s.clientRegion(ctx))
+	}
 
-	dialCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
-	conn, err := grpc.DialContext(dialCtx, target, grpc.WithInsecure(), grpc.WithBlock())
-	cancel()
-	if err != nil {
-		return nil, fmt.Errorf("dial %s: %w", target, err)
+	tried := make(map[string]bool)
+	var err error
+	for len(replicas) > 0 && ctx.Err() == nil {
+		target := replicas[0]
+		replicas = replicas[1:]
+		if tried[target] {
+			continue
+		}
+		tried[target] = true
+		var resp *proto.GetReply
+		resp, err = s.get(ctx, target, req)
+		if err == nil {
+			return resp, nil
+		}
+		if rd, ok := kvstore.Redirected(err); ok {
+			replicas = append(rd.Owners, replicas...)
+			continue
+		}
+		if !health.IsNodeFailure(err) {
+			break
+		}
 	}
-	defer conn.Close()
+	return nil, err
+}
 
-	client := proto.NewKVClient(conn)
-	resp, err := client.Get(ctx, req)
+// get reads req from a single node.
+func (s *Server) get(ctx context.Context, addr string, req *proto.GetRequest) (*proto.GetReply, error) {
+	conn, err := s.dial(ctx, addr)
+	var resp *proto.GetReply
+	if err == nil {
+		defer conn.Close()
+		resp, err = proto.NewKVClient(conn).Get(ctx, req)
+	}
+	s.Health.Observe(addr, err)
 	if err != nil {
-		return nil, fmt.Errorf("get from %s: %w", target, err)
+		return nil, fmt.Errorf("get from %s: %w", addr, err)
 	}
 	return resp, nil
 }
+
+// Delete removes the key from every replica, and from substitutes holding
+// hinted writes of it, whose handoff would otherwise bring it back. It is
+// stamped with a version like Put, so a replica holding a newer write
+// keeps it, and follows redirects from servers that no longer own the key.
+// It succeeds once WriteQuorum targets acknowledge it.
+func (s *Server) Delete(ctx context.Context, req *proto.DeleteRequest) (*proto.DeleteReply, error) {
+	replicas := s.ring.GetReplicaList(req.Key, s.R)
+	if len(replicas) == 0 {
+		return nil, fmt.Errorf("no replicas for key %q", req.Key)
+	}
+	if req.Version == 0 {
+		req.Version = uint64(time.Now().UnixNano())
+	}
+	req.Epoch = s.ring.Epoch()
+	holders := s.dropHints(req.Key)
+
+	acks, deleted := 0, false
+	var firstErr error
+	used := make(map[string]bool)
+	for _, addr := range replicas {
+		used[addr] = true
+	}
+	for targets := replicas; len(targets) > 0 && ctx.Err() == nil; {
+		var retry []string
+		for _, addr := range targets {
+			ok, err := s.delete(ctx, addr, req)
+			if err == nil {
+				deleted = deleted || ok
+				acks++
+				continue
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			if rd, ok := kvstore.Redirected(err); ok {
+				for _, node := range rd.Owners {
+					if !used[node] {
+						used[node] = true
+						retry = append(retry, node)
+					}
+				}
+			}
+		}
+		targets = retry
+	}
+	// substitutes need not own the key, so they are not fenced
+	hinted := &proto.DeleteRequest{Key: req.Key, Version: req.Version}
+	for _, addr := range minus(holders, replicas) {
+		ok, err := s.delete(ctx, addr, hinted)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		deleted = deleted || ok
+	}
+	if quorum := s.writeQuorum(len(replicas)); acks < quorum {
+		if _, ok := kvstore.Redirected(firstErr); ok {
+			// routing is behind the servers; the client may retry
+			return nil, status.Errorf(codes.FailedPrecondition, "delete %q: %v", req.Key, firstErr)
+		}
+		return nil, status.Errorf(codes.Unavailable, "delete %q: %d of %d deletes acknowledged: %v", req.Key, acks, quorum, firstErr)
+	}
+	return &proto.DeleteReply{Deleted: deleted}, nil
+}
+
+// delete removes req.Key from a single node.
+func (s *Server) delete(ctx context.Context, addr string, req *proto.DeleteRequest) (bool, error) {
+	conn, err := s.dial(ctx, addr)
+	var resp *proto.DeleteReply
+	if err == nil {
+		defer conn.Close()
+		resp, err = proto.NewKVClient(conn).Delete(ctx, req)
+	}
+	s.Health.Observe(addr, err)
+	if err != nil {
+		return false, fmt.Errorf("delete from %s: %w", addr, err)
+	}
+	return resp.Deleted, nil
+}
+
+// dial connects to a storage node, giving up after dialTimeout.
+func (s *Server) dial(ctx context.Context, addr string) (*grpc.ClientConn, error) {
+	dialCtx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+	opts := append([]grpc.DialOption{grpc.WithInsecure(), grpc.WithBlock()}, s.DialOptions...)
+	return grpc.DialContext(dialCtx, addr, opts...)
+}
+
+// clientRegion returns the region named in the request metadata, falling
+// back to the proxy's own region. Only regions of the ring are accepted:
+// every region reported costs the manager a tracker, so clients must not be
+// able to make them up.
+func (s *Server) clientRegion(ctx context.Context) string {
+	if md, ok := grpcmd.FromIncomingContext(ctx); ok {
+		if v := md.Get(RegionHeader); len(v) > 0 && s.ring.HasRegion(v[0]) {
+			return v[0]
+		}
+	}
+	return s.Region
+}
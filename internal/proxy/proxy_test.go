@@ -0,0 +1,230 @@
+// internal/proxy/proxy_test.go
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcmd "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/kvstore"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/metadata"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/proto"
+)
+
+// testNode is an in-process storage server.
+type testNode struct {
+	addr  string
+	store *kvstore.KVStore
+	svc   *kvstore.Service
+	srv   *grpc.Server
+}
+
+// startNode serves a fresh store on addr, or on a free port if addr is "".
+func startNode(t *testing.T, addr string) *testNode {
+	t.Helper()
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := kvstore.NewWALStore(filepath.Join(t.TempDir(), "wal.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := &testNode{addr: lis.Addr().String(), store: store, svc: kvstore.NewService(store), srv: grpc.NewServer()}
+	proto.RegisterKVServer(n.srv, n.svc)
+	go n.srv.Serve(lis)
+	t.Cleanup(n.stop)
+	return n
+}
+
+func (n *testNode) stop() {
+	n.srv.Stop()
+	n.store.Close()
+}
+
+// testCluster starts nodes storage servers, one region per entry of
+// regions, and a proxy routing to them with R replicas through a ring
+// that follows a Memory store.
+func testCluster(t *testing.T, R int, regions ...string) (*Server, *metadata.Memory, []*testNode) {
+	t.Helper()
+	md := metadata.NewMemory()
+	ring := hashring.New(20)
+	md.WatchRingConfig(ring.Update)
+	md.WatchResidency(ring.UpdateResidency)
+
+	cfg := hashring.Config{VNodes: 20, Regions: make(map[string]string)}
+	nodes := make([]*testNode, len(regions))
+	for i, region := range regions {
+		nodes[i] = startNode(t, "")
+		cfg.Nodes = append(cfg.Nodes, nodes[i].addr)
+		cfg.Regions[nodes[i].addr] = region
+	}
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := md.SetRingConfig(raw, metadata.AnyRevision); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return ring.Epoch() == 1 })
+	return NewProxyServer(ring, md, R), md, nodes
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met after 5s")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func nodeByAddr(nodes []*testNode, addr string) *testNode {
+	for _, n := range nodes {
+		if n.addr == addr {
+			return n
+		}
+	}
+	return nil
+}
+
+func TestClusterPutGetDelete(t *testing.T) {
+	p, _, nodes := testCluster(t, 2, "eu", "eu", "us")
+	ctx := context.Background()
+
+	put, err := p.Put(ctx, &proto.PutRequest{Key: "k", Value: []byte("v1")})
+	if err != nil || !put.Applied {
+		t.Fatalf("Put = %v, %v", put, err)
+	}
+	replicas := p.ring.GetReplicaList("k", 2)
+	for _, n := range nodes {
+		_, stored := n.store.Get("k")
+		if stored != contains(replicas, n.addr) {
+			t.Fatalf("node %s holds k: %v, replicas %v", n.addr, stored, replicas)
+		}
+	}
+	get, err := p.Get(ctx, &proto.GetRequest{Key: "k"})
+	if err != nil || !get.Found || string(get.Value) != "v1" || get.Version != put.Version {
+		t.Fatalf("Get = %v, %v; want v1 at version %d", get, err, put.Version)
+	}
+
+	del, err := p.Delete(ctx, &proto.DeleteRequest{Key: "k"})
+	if err != nil || !del.Deleted {
+		t.Fatalf("Delete = %v, %v", del, err)
+	}
+	if get, err := p.Get(ctx, &proto.GetRequest{Key: "k"}); err != nil || get.Found {
+		t.Fatalf("Get after Delete = %v, %v", get, err)
+	}
+	// a write older than the delete stays deleted
+	if _, err := p.Put(ctx, &proto.PutRequest{Key: "k", Value: []byte("old"), Version: put.Version}); err != nil {
+		t.Fatal(err)
+	}
+	if get, err := p.Get(ctx, &proto.GetRequest{Key: "k"}); err != nil || get.Found {
+		t.Fatalf("Get after a stale Put = %v, %v; want the delete to hold", get, err)
+	}
+}
+
+func TestClusterHintedHandoff(t *testing.T) {
+	p, _, nodes := testCluster(t, 2, "eu", "eu", "us")
+	ctx := context.Background()
+	replicas := p.ring.GetReplicaList("k", 2)
+	down := nodeByAddr(nodes, replicas[1])
+	down.stop()
+
+	// the write goes to the remaining node in place of the failed replica
+	if _, err := p.Put(ctx, &proto.PutRequest{Key: "k", Value: []byte("v")}); err != nil {
+		t.Fatalf("Put with a replica down: %v", err)
+	}
+	substitute := nodeByAddr(nodes, minus(p.ring.AllNodes(), replicas)[0])
+	if v, ok := substitute.store.Get("k"); !ok || string(v) != "v" {
+		t.Fatalf("substitute holds %q, %v", v, ok)
+	}
+
+	// once the replica is back, the write is handed off and the
+	// substitute's copy dropped
+	back := startNode(t, down.addr)
+	p.handoff(ctx)
+	if v, ok := back.store.Get("k"); !ok || string(v) != "v" {
+		t.Fatalf("recovered replica holds %q, %v after handoff", v, ok)
+	}
+	if _, ok := substitute.store.Get("k"); ok {
+		t.Fatal("substitute kept its copy after handoff")
+	}
+}
+
+func TestClusterResidencyRedirect(t *testing.T) {
+	p, md, nodes := testCluster(t, 2, "eu", "eu", "us")
+	md.SetResidencyRule("eu", []byte(`{"prefix": "eu/", "regions": ["eu"]}`))
+	waitFor(t, func() bool { return p.ring.CheckResidency("eu/k", []string{nodes[2].addr}) != nil })
+
+	// the servers have moved on to a configuration the proxy has not seen,
+	// which hands every key to the us node
+	serverRing := hashring.New(20)
+	serverRing.Update([]byte(`{"nodes": ["` + nodes[2].addr + `"], "epoch": 2}`))
+	for _, n := range nodes {
+		n.svc.Ring, n.svc.NodeID, n.svc.R = serverRing, n.addr, 2
+	}
+
+	_, err := p.Put(context.Background(), &proto.PutRequest{Key: "eu/k", Value: []byte("v")})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Put redirected out of its region = %v, want FailedPrecondition", err)
+	}
+	if _, ok := nodes[2].store.Get("eu/k"); ok {
+		t.Fatal("key written outside its residency region")
+	}
+}
+
+func TestClientRegionOnlyAcceptsRingRegions(t *testing.T) {
+	p, _, _ := testCluster(t, 2, "eu", "us")
+	p.Region = "eu"
+	for header, want := range map[string]string{"us": "us", "": "eu", "mars": "eu"} {
+		ctx := grpcmd.NewIncomingContext(context.Background(), grpcmd.Pairs(RegionHeader, header))
+		if got := p.clientRegion(ctx); got != want {
+			t.Errorf("clientRegion with %s=%q = %q, want %q", RegionHeader, header, got, want)
+		}
+	}
+}
+
+func TestClusterDeleteFollowsRedirect(t *testing.T) {
+	p, _, nodes := testCluster(t, 2, "eu", "eu", "us")
+	owners := []*testNode{startNode(t, ""), startNode(t, "")}
+	for _, n := range append(nodes, owners...) {
+		n.store.Put("k", []byte("v"), 1)
+	}
+
+	// the servers have moved the key to two nodes the proxy does not know
+	serverRing := hashring.New(20)
+	serverRing.Update([]byte(`{"nodes": ["` + owners[0].addr + `", "` + owners[1].addr + `"], "epoch": 2}`))
+	for _, n := range append(nodes, owners...) {
+		n.svc.Ring, n.svc.NodeID, n.svc.R = serverRing, n.addr, 2
+	}
+
+	del, err := p.Delete(context.Background(), &proto.DeleteRequest{Key: "k"})
+	if err != nil || !del.Deleted {
+		t.Fatalf("Delete = %v, %v", del, err)
+	}
+	for _, n := range owners {
+		if _, ok := n.store.Get("k"); ok {
+			t.Fatalf("current owner %s still holds k", n.addr)
+		}
+	}
+	// former owners refuse a delete routed with the old ring
+	for _, node := range p.ring.GetReplicaList("k", 2) {
+		if _, ok := nodeByAddr(nodes, node).store.Get("k"); !ok {
+			t.Fatalf("former owner %s applied a delete routed at an old epoch", node)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// internal/ratelimit/ratelimit.go
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket that refills at a fixed rate per second.
+// A nil *Limiter, or one with a non-positive rate, never blocks.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// New returns a limiter allowing rate tokens per second with bursts of up to
+// one second's worth of tokens.
+func New(rate float64) *Limiter {
+	return &Limiter{rate: rate, burst: rate, tokens: rate, last: time.Now()}
+}
+
+// Wait blocks until n tokens are available or ctx is done. Requests larger
+// than the burst are allowed to drive the bucket negative, so big items are
+// paced rather than rejected.
+func (l *Limiter) Wait(ctx context.Context, n int) error {
+	if l == nil || l.rate <= 0 {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	deficit := -l.tokens
+	l.mu.Unlock()
+
+	if deficit <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(time.Duration(deficit / l.rate * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,50 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/proto"
+)
+
+// AdminService implements proto.AdminServer: operators fetch dry-run plans
+// and approve them.
+type AdminService struct {
+	proto.UnimplementedAdminServer
+	m *Manager
+}
+
+// NewAdminService returns the admin service for m.
+func NewAdminService(m *Manager) *AdminService {
+	return &AdminService{m: m}
+}
+
+// Plan computes a fresh plan without applying it.
+func (s *AdminService) Plan(ctx context.Context, req *proto.PlanRequest) (*proto.PlanReply, error) {
+	p := s.m.Plan(ctx)
+	buf, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.PlanReply{Id: p.ID, PlanJson: string(buf)}, nil
+}
+
+// ApplyPlan applies an approved plan.
+func (s *AdminService) ApplyPlan(ctx context.Context, req *proto.ApplyPlanRequest) (*proto.ApplyPlanReply, error) {
+	applied, failed, err := s.m.ApplyPlan(ctx, req.Id, req.Keys)
+	if errors.Is(err, ErrNotLeader) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	reply := &proto.ApplyPlanReply{Applied: applied, Failed: make(map[string]string, len(failed))}
+	for key, err := range failed {
+		reply.Failed[key] = err.Error()
+	}
+	return reply, nil
+}
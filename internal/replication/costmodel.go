@@ -0,0 +1,285 @@
+package replication
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
+)
+
+// CostModelConfig holds the inputs of the built-in cost model. Costs are in
+// an arbitrary currency. Access stats are rates, so egress costs and Budget
+// are per second; storage is charged at StorageCostPerGB per second too.
+type CostModelConfig struct {
+	// LatencyMs[from][to] is the round trip between two regions.
+	LatencyMs map[string]map[string]float64 `json:"latency_ms"`
+	// DefaultLatencyMs is used for region pairs missing from LatencyMs
+	// (a region to itself defaults to 0).
+	DefaultLatencyMs float64 `json:"default_latency_ms"`
+	// StorageCostPerGB is charged once per replica.
+	StorageCostPerGB float64 `json:"storage_cost_per_gb"`
+	// EgressCostPerGB is charged for every operation served across regions.
+	EgressCostPerGB float64 `json:"egress_cost_per_gb"`
+	// ObjectBytes is the assumed size of a value.
+	ObjectBytes float64 `json:"object_bytes"`
+	// Budget caps the expected cost of a key's replica set; 0 is unlimited.
+	Budget float64 `json:"budget"`
+	// MinGainMs is the expected-latency improvement an addition must bring,
+	// which keeps marginal replicas from being added and removed again.
+	MinGainMs float64 `json:"min_gain_ms"`
+}
+
+// DefaultCostModelConfig is used when no configuration is given: every pair
+// of regions is 100ms apart and replicas are free, so keys gain replicas in
+// the regions that read them until the replica bounds or the write fan-out
+// latency stop them.
+var DefaultCostModelConfig = CostModelConfig{
+	DefaultLatencyMs: 100,
+	MinGainMs:        10,
+}
+
+// LoadCostModelConfig reads a JSON CostModelConfig from path.
+func LoadCostModelConfig(path string) (CostModelConfig, error) {
+	var cfg CostModelConfig
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	err = json.Unmarshal(raw, &cfg)
+	return cfg, err
+}
+
+// CostModel is a Policy that places replicas by region so as to minimize the
+// expected latency of a key's reads and writes within a cost budget. It
+// assumes reads are served by the nearest replica and writes fan out to all
+// replicas in parallel, so a write costs the latency of the farthest one.
+type CostModel struct {
+	ring *hashring.Ring
+	cfg  CostModelConfig
+}
+
+// NewCostModel returns the cost model; node regions come from the ring.
+func NewCostModel(r *hashring.Ring, cfg CostModelConfig) *CostModel {
+	return &CostModel{ring: r, cfg: cfg}
+}
+
+// maxExhaustiveRegions bounds the exhaustive search over region subsets;
+// beyond it the model grows the set greedily.
+const maxExhaustiveRegions = 12
+
+// Decide picks the best set of regions for the key and translates it into
+// node decisions: add one node in every chosen region without a replica,
+// remove replicas in regions that were not chosen. Add scores are the
+// latency gain in ms, remove scores the cost saved.
+func (c *CostModel) Decide(key string, current, candidates []string, stats KeyStats) []Decision {
+	byRegion := make(map[string][]string)
+	for _, node := range candidates {
+		region := c.ring.Region(node)
+		byRegion[region] = append(byRegion[region], node)
+	}
+	regions := make([]string, 0, len(byRegion))
+	for region := range byRegion {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	have := make(map[string]bool)
+	for _, node := range current {
+		have[c.ring.Region(node)] = true
+	}
+	currentSet := keysOf(have)
+	best := c.best(regions, stats)
+	if best == nil {
+		return nil
+	}
+	curLat, curCost := c.Evaluate(currentSet, stats)
+	bestLat, bestCost := c.Evaluate(best, stats)
+	// only move if the gain is worth it, or the current set breaks the budget
+	if curLat-bestLat < c.cfg.MinGainMs && (c.cfg.Budget == 0 || curCost <= c.cfg.Budget) {
+		return nil
+	}
+
+	chosen := make(map[string]bool, len(best))
+	for _, region := range best {
+		chosen[region] = true
+	}
+	var out []Decision
+	for _, region := range best {
+		if have[region] {
+			continue
+		}
+		// score by how much worse the best set would be without the region
+		lat, _ := c.Evaluate(removeString(best, region), stats)
+		out = append(out, Decision{
+			Node:   byRegion[region][0],
+			Action: Add,
+			Score:  finite(lat - bestLat),
+			Reason: fmt.Sprintf("replica in %s cuts expected latency by %.1fms", region, lat-bestLat),
+		})
+	}
+	for _, node := range current {
+		if region := c.ring.Region(node); !chosen[region] {
+			out = append(out, Decision{
+				Node:   node,
+				Action: Remove,
+				Score:  finite(curCost - bestCost),
+				Reason: fmt.Sprintf("region %s not in the best set %v, saves %.4g cost", region, best, curCost-bestCost),
+			})
+		}
+	}
+	return out
+}
+
+// best returns the region set with the lowest expected latency whose cost
+// fits the budget, preferring cheaper sets on ties.
+func (c *CostModel) best(regions []string, stats KeyStats) []string {
+	if len(regions) == 0 {
+		return nil
+	}
+	if len(regions) > maxExhaustiveRegions {
+		return c.greedy(regions, stats)
+	}
+	var best []string
+	bestLat, bestCost := math.Inf(1), math.Inf(1)
+	for mask := 1; mask < 1<<len(regions); mask++ {
+		set := make([]string, 0, len(regions))
+		for i, region := range regions {
+			if mask&(1<<i) != 0 {
+				set = append(set, region)
+			}
+		}
+		lat, cost := c.Evaluate(set, stats)
+		if c.cfg.Budget > 0 && cost > c.cfg.Budget {
+			continue
+		}
+		if lat < bestLat || (lat == bestLat && cost < bestCost) {
+			best, bestLat, bestCost = set, lat, cost
+		}
+	}
+	return best
+}
+
+// greedy grows the region set one region at a time, taking the region with
+// the largest latency reduction that keeps the cost within budget.
+func (c *CostModel) greedy(regions []string, stats KeyStats) []string {
+	var set []string
+	curLat := math.Inf(1)
+	for {
+		var pick string
+		pickLat := curLat
+		for _, region := range regions {
+			if contains(set, region) {
+				continue
+			}
+			lat, cost := c.Evaluate(append(set[:len(set):len(set)], region), stats)
+			if c.cfg.Budget > 0 && cost > c.cfg.Budget {
+				continue
+			}
+			if lat < pickLat {
+				pick, pickLat = region, lat
+			}
+		}
+		if pick == "" {
+			return set
+		}
+		set, curLat = append(set, pick), pickLat
+	}
+}
+
+// Estimate implements Estimator by evaluating the regions of nodes.
+func (c *CostModel) Estimate(nodes []string, stats KeyStats) (latencyMs, cost float64) {
+	regions := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		regions[c.ring.Region(node)] = true
+	}
+	lat, cost := c.Evaluate(keysOf(regions), stats)
+	return finite(lat), finite(cost)
+}
+
+// Evaluate returns the expected per-operation latency and the total cost of
+// serving stats from replicas in the given regions.
+func (c *CostModel) Evaluate(regions []string, stats KeyStats) (latencyMs, cost float64) {
+	if len(regions) == 0 {
+		return math.Inf(1), math.Inf(1)
+	}
+	gb := c.cfg.ObjectBytes / (1 << 30)
+	cost = float64(len(regions)) * gb * c.cfg.StorageCostPerGB
+
+	var ops, total float64
+	for client, n := range stats.Reads {
+		nearest, lat := c.nearest(client, regions)
+		total += n * lat
+		ops += n
+		if nearest != client {
+			cost += n * gb * c.cfg.EgressCostPerGB
+		}
+	}
+	for client, n := range stats.Writes {
+		var worst float64
+		for _, region := range regions {
+			worst = math.Max(worst, c.latency(client, region))
+			if region != client {
+				cost += n * gb * c.cfg.EgressCostPerGB
+			}
+		}
+		total += n * worst
+		ops += n
+	}
+	if ops == 0 {
+		return 0, cost
+	}
+	return total / ops, cost
+}
+
+// nearest returns the replica region closest to client and its latency.
+func (c *CostModel) nearest(client string, regions []string) (string, float64) {
+	best, bestLat := "", math.Inf(1)
+	for _, region := range regions {
+		if lat := c.latency(client, region); lat < bestLat {
+			best, bestLat = region, lat
+		}
+	}
+	return best, bestLat
+}
+
+// latency looks up the round trip between two regions.
+func (c *CostModel) latency(from, to string) float64 {
+	if lat, ok := c.cfg.LatencyMs[from][to]; ok {
+		return lat
+	}
+	if lat, ok := c.cfg.LatencyMs[to][from]; ok {
+		return lat
+	}
+	if from == to {
+		return 0
+	}
+	return c.cfg.DefaultLatencyMs
+}
+
+// finite clamps infinities, which arise from empty replica sets, so scores
+// stay comparable and serializable.
+func finite(f float64) float64 {
+	return math.Max(-math.MaxFloat64, math.Min(f, math.MaxFloat64))
+}
+
+func keysOf(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func removeString(slice []string, s string) []string {
+	out := make([]string, 0, len(slice))
+	for _, v := range slice {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,120 @@
+// internal/replication/costmodel_test.go
+package replication
+
+import (
+	"testing"
+)
+
+var testRegions = []string{"us-east", "eu-west", "ap-south"}
+
+// testCostModel prices three regions: us-east and eu-west 80ms apart,
+// ap-south 200ms from both, on a ring with one node per region ("n0" in
+// us-east, "n1" in eu-west, "n2" in ap-south). A replica costs 1 and an
+// operation served across regions 0.5.
+func testCostModel(t *testing.T, budget float64) *CostModel {
+	ring := testRing(t, 3, func(i int) string { return testRegions[i] })
+	return NewCostModel(ring, CostModelConfig{
+		LatencyMs: map[string]map[string]float64{
+			"us-east": {"eu-west": 80, "ap-south": 200},
+			"eu-west": {"ap-south": 200},
+		},
+		DefaultLatencyMs: 300,
+		StorageCostPerGB: 1,
+		EgressCostPerGB:  0.5,
+		ObjectBytes:      1 << 30,
+		Budget:           budget,
+		MinGainMs:        5,
+	})
+}
+
+func TestCostModelLatency(t *testing.T) {
+	c := testCostModel(t, 0)
+	for _, tc := range []struct {
+		from, to string
+		want     float64
+	}{
+		{"us-east", "eu-west", 80},
+		{"eu-west", "us-east", 80}, // symmetric when only one direction is given
+		{"ap-south", "us-east", 200},
+		{"eu-west", "eu-west", 0},   // a region to itself
+		{"us-east", "sa-east", 300}, // missing pairs use the default
+	} {
+		if got := c.latency(tc.from, tc.to); got != tc.want {
+			t.Errorf("latency(%s, %s) = %v, want %v", tc.from, tc.to, got, tc.want)
+		}
+	}
+}
+
+func TestCostModelReadHeavyAddsReplicasNearReaders(t *testing.T) {
+	c := testCostModel(t, 0)
+	stats := KeyStats{
+		Reads:  map[string]float64{"us-east": 100, "eu-west": 100},
+		Writes: map[string]float64{"us-east": 1},
+	}
+	decisions := c.Decide("k", []string{"n0"}, []string{"n0", "n1", "n2"}, stats)
+	if !hasDecision(decisions, "n1", Add) {
+		t.Fatalf("decisions %v do not add the eu-west replica", decisions)
+	}
+	if hasDecision(decisions, "n2", Add) {
+		t.Fatalf("decisions %v add ap-south, which has no readers", decisions)
+	}
+}
+
+func TestCostModelWriteHeavyDoesNotFanOut(t *testing.T) {
+	c := testCostModel(t, 0)
+	stats := KeyStats{
+		Reads:  map[string]float64{"us-east": 1, "eu-west": 1},
+		Writes: map[string]float64{"us-east": 100},
+	}
+	decisions := c.Decide("k", []string{"n0"}, []string{"n0", "n1", "n2"}, stats)
+	for _, d := range decisions {
+		if d.Action == Add {
+			t.Fatalf("write-heavy key gets replica %s: %v", d.Node, decisions)
+		}
+	}
+	// and a write-heavy key already spread out is pulled back to its writers
+	decisions = c.Decide("k", []string{"n0", "n1", "n2"}, []string{"n0", "n1", "n2"}, stats)
+	if !hasDecision(decisions, "n2", Remove) {
+		t.Fatalf("decisions %v keep the distant ap-south replica", decisions)
+	}
+}
+
+func TestCostModelBudget(t *testing.T) {
+	stats := KeyStats{Reads: map[string]float64{"us-east": 1, "eu-west": 1, "ap-south": 1}}
+	unlimited := testCostModel(t, 0)
+	if best := unlimited.best(testRegions, stats); len(best) != 3 {
+		t.Fatalf("without a budget, best = %v, want every reader's region", best)
+	}
+
+	// a replica costs 1 and a remote read 0.5: one replica costs 2, two
+	// cost 2.5 and three cost 3, so a budget of 2.5 allows two regions
+	limited := testCostModel(t, 2.5)
+	best := limited.best(testRegions, stats)
+	if best == nil {
+		t.Fatal("no region set fits the budget")
+	}
+	if _, cost := limited.Evaluate(best, stats); len(best) != 2 || cost > 2.5 {
+		t.Fatalf("best = %v costs %v, want two regions within the budget of 2.5", best, cost)
+	}
+
+	// a current set over budget is cut back even without a latency gain
+	decisions := limited.Decide("k", []string{"n0", "n1", "n2"}, []string{"n0", "n1", "n2"}, stats)
+	removes := 0
+	for _, d := range decisions {
+		if d.Action == Remove {
+			removes++
+		}
+	}
+	if removes == 0 {
+		t.Fatalf("decisions %v leave an over-budget replica set", decisions)
+	}
+}
+
+func hasDecision(decisions []Decision, node string, action Action) bool {
+	for _, d := range decisions {
+		if d.Node == node && d.Action == action {
+			return true
+		}
+	}
+	return false
+}
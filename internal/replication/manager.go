@@ -3,64 +3,184 @@ package replication
 import (
 	"context"
 	"fmt"
+	"log"
+	"math"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
 	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/metadata"
 )
 
-// DecisionFunc is your cost model: for key x and node y, should we add/remove?
-type DecisionFunc func(key, node string) (shouldAdd bool, score float64)
-
 // Manager drives adaptive placement.
 type Manager struct {
 	ring   *hashring.Ring
-	md     *metadata.Client
+	md     metadata.Store
 	R      int
-	decide DecisionFunc
+	policy Policy
+	mover  *Mover
+
+	// MinReplicas and MaxReplicas bound every key's replica count.
+	MinReplicas int
+	MaxReplicas int
+	// Confirmations is how many consecutive evaluations must propose the
+	// same replica list before it is applied.
+	Confirmations int
+	// Cooldown is the minimum time between two changes to the same key.
+	Cooldown time.Duration
+	// HalfLife is how quickly access rates forget old traffic; a
+	// non-positive value means DefaultHalfLife.
+	HalfLife time.Duration
+	// MinRate is the rate in ops/s below which a key is considered cold in
+	// a region and not evaluated.
+	MinRate float64
+	// TopK is the number of heavy hitters tracked per region and operation;
+	// SketchWidth and SketchDepth size the count-min sketch behind them.
+	// Memory is bounded by these, not by the number of keys. Non-positive
+	// values mean the defaults.
+	TopK        int
+	SketchWidth int
+	SketchDepth int
+	// PrimaryMargin is how many times more traffic a region must generate
+	// than the primary's region before the primary moves there, and
+	// PrimaryCooldown the minimum time between two primary moves of a key.
+	// Together they keep the primary from ping-ponging between regions
+	// whose traffic crosses over. A zero margin disables primary moves.
+	PrimaryMargin   float64
+	PrimaryCooldown time.Duration
+	// MaxChangesPerRound is the budget of changes applied per evaluation;
+	// the highest-priority ready changes go first and the rest wait for the
+	// next round. MaxConcurrentMoves and MaxMovesPerNode bound the moves in
+	// flight overall and per node. MoveBytesPerSec and NodeMoveBytesPerSec
+	// cap their copy bandwidth likewise. Zero means unlimited.
+	MaxChangesPerRound  int
+	MaxConcurrentMoves  int
+	MaxMovesPerNode     int
+	MoveBytesPerSec     float64
+	NodeMoveBytesPerSec float64
+	// DryRun makes the decision loop only plan: nothing is written to etcd
+	// and no data moves. OnPlan, if set, receives every round's plan.
+	DryRun bool
+	OnPlan func(*Plan)
 
-	// in-memory metrics; you can swap for a more scalable store
+	// bounded-memory access metrics, created per region on first use
 	mu     sync.Mutex
-	reads  map[string]map[string]int // reads[key][region]
-	writes map[string]map[string]int // writes[key][region]
+	reads  map[string]*accessTracker // reads[region]
+	writes map[string]*accessTracker // writes[region]
+
+	// hysteresis state and operator plans awaiting approval
+	planMu      sync.Mutex
+	pending     map[string]proposal  // key -> proposal awaiting confirmation
+	lastChanged map[string]time.Time // key -> time of last applied change
+	lastPrimary map[string]time.Time // key -> time of last primary move
+	plans       map[string]*Plan
+	planOrder   []string // plan IDs, oldest first
+
+	leading atomic.Bool // Run is active, i.e. this instance is leader
+
+	slots *moveSlots
+	bw    bandwidth
 }
 
-// NewManager constructs the replica manager.
-func NewManager(r *hashring.Ring, md *metadata.Client, R int, decide DecisionFunc) *Manager {
-	return &Manager{
-		ring:   r,
-		md:     md,
-		R:      R,
-		decide: decide,
-		reads:  make(map[string]map[string]int),
-		writes: make(map[string]map[string]int),
+// proposal is a replica list waiting for enough confirmations.
+type proposal struct {
+	target []string
+	seen   int
+}
+
+// NewManager constructs the replica manager. A DecisionFunc may be passed as
+// the policy. Keys keep between R and 2R replicas unless the bounds are
+// changed before Run.
+func NewManager(r *hashring.Ring, md metadata.Store, R int, policy Policy) *Manager {
+	m := &Manager{
+		ring:               r,
+		md:                 md,
+		R:                  R,
+		policy:             policy,
+		mover:              NewMover(r, md, R),
+		MinReplicas:        R,
+		MaxReplicas:        2 * R,
+		Confirmations:      3,
+		Cooldown:           5 * time.Minute,
+		HalfLife:           DefaultHalfLife,
+		MinRate:            1.0 / (24 * 60 * 60), // once a day
+		TopK:               DefaultTopK,
+		SketchWidth:        DefaultSketchWidth,
+		SketchDepth:        DefaultSketchDepth,
+		PrimaryMargin:      1.5,
+		PrimaryCooldown:    time.Hour,
+		MaxChangesPerRound: 100,
+		MaxConcurrentMoves: 8,
+		MaxMovesPerNode:    2,
+		reads:              make(map[string]*accessTracker),
+		writes:             make(map[string]*accessTracker),
+		pending:            make(map[string]proposal),
+		lastChanged:        make(map[string]time.Time),
+		lastPrimary:        make(map[string]time.Time),
+		plans:              make(map[string]*Plan),
+		slots:              newMoveSlots(),
+	}
+	m.mover.Throttle = func(ctx context.Context, nodes []string, bytes int) error {
+		return m.bw.charge(ctx, nodes, bytes, m.MoveBytesPerSec, m.NodeMoveBytesPerSec)
 	}
+	return m
 }
 
 // RecordRead logs a single Get(key) from region.
 func (m *Manager) RecordRead(key, region string) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	if _, ok := m.reads[key]; !ok {
-		m.reads[key] = make(map[string]int)
-	}
-	m.reads[key][region]++
+	m.RecordReads(key, region, 1)
 }
 
 // RecordWrite logs a single Put(key) from region.
 func (m *Manager) RecordWrite(key, region string) {
+	m.RecordWrites(key, region, 1)
+}
+
+// RecordReads logs n Get(key) calls from region.
+func (m *Manager) RecordReads(key, region string, n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tracker(m.reads, region).add(key, float64(n), time.Now())
+}
+
+// RecordWrites logs n Put(key) calls from region.
+func (m *Manager) RecordWrites(key, region string, n int) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if _, ok := m.writes[key]; !ok {
-		m.writes[key] = make(map[string]int)
+	m.tracker(m.writes, region).add(key, float64(n), time.Now())
+}
+
+// tracker returns the region's tracker, creating it if needed. Caller holds m.mu.
+func (m *Manager) tracker(trackers map[string]*accessTracker, region string) *accessTracker {
+	t, ok := trackers[region]
+	if !ok {
+		// zero sizes would panic in the sketches
+		halfLife, width, depth, k := m.HalfLife, m.SketchWidth, m.SketchDepth, m.TopK
+		if halfLife <= 0 {
+			halfLife = DefaultHalfLife
+		}
+		if width <= 0 {
+			width = DefaultSketchWidth
+		}
+		if depth <= 0 {
+			depth = DefaultSketchDepth
+		}
+		if k <= 0 {
+			k = DefaultTopK
+		}
+		t = newAccessTracker(halfLife, width, depth, k, time.Now())
+		trackers[region] = t
 	}
-	m.writes[key][region]++
+	return t
 }
 
 // Run starts the periodic decision loop.
 // It polls metrics, runs the cost model, and updates etcd when needed.
 func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	m.leading.Store(true)
+	defer m.leading.Store(false)
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	for {
@@ -73,33 +193,234 @@ func (m *Manager) Run(ctx context.Context, interval time.Duration) {
 	}
 }
 
+// evaluateAllKeys plans a round and applies the changes that made it
+// through the hysteresis, unless in dry-run mode.
 func (m *Manager) evaluateAllKeys(ctx context.Context) {
+	plan := m.plan(ctx)
+	if m.OnPlan != nil {
+		m.OnPlan(plan)
+	}
+
+	proposed := make(map[string]bool, len(plan.Changes))
+	var ready []Change
+	for _, ch := range plan.Changes {
+		proposed[ch.Key] = true
+		if m.advance(ch.Key, ch.Proposed) && !m.DryRun {
+			ready = append(ready, ch)
+		}
+	}
+	// deferred changes keep their confirmations and go first next round
+	// if they still rank highest
+	_, failed, deferred := m.applyAll(ctx, ready, m.MaxChangesPerRound)
+	for key, err := range failed {
+		log.Printf("replica update error for %s: %v", key, err)
+	}
+	if len(deferred) > 0 {
+		log.Printf("change budget of %d reached, deferred %d changes", m.MaxChangesPerRound, len(deferred))
+	}
+
+	m.planMu.Lock()
+	defer m.planMu.Unlock()
+	// a key that no longer proposes a change restarts its confirmations
+	for key := range m.pending {
+		if !proposed[key] {
+			delete(m.pending, key)
+		}
+	}
+	for key, t := range m.lastChanged {
+		if time.Since(t) >= m.Cooldown {
+			delete(m.lastChanged, key)
+		}
+	}
+	for key, t := range m.lastPrimary {
+		if time.Since(t) >= m.PrimaryCooldown {
+			delete(m.lastPrimary, key)
+		}
+	}
+}
+
+// hotKeys returns the union of every region's heavy hitters, the only keys
+// worth evaluating.
+func (m *Manager) hotKeys() []string {
 	m.mu.Lock()
-	keys := make([]string, 0, len(m.reads))
-	for k := range m.reads {
-		keys = append(keys, k)
-	}
-	m.mu.Unlock()
-
-	for _, key := range keys {
-		// for simplicity, consider all nodes in ring.nodes
-		// you could restrict to regions seen in metrics
-		for _, node := range m.ring.AllNodes() {
-			add, score := m.decide(key, node)
-			if add {
-				// fetch current list, append node if missing
-				current := m.ring.GetReplicaList(key, m.R)
-				if !contains(current, node) {
-					newList := append(current, node)
-					// write to etcd
-					if err := m.md.SetReplicas(key, newList); err != nil {
-						fmt.Printf("replica update error for %s: %v\n", key, err)
-					}
+	defer m.mu.Unlock()
+	now := time.Now()
+	seen := make(map[string]bool)
+	var keys []string
+	for _, trackers := range []map[string]*accessTracker{m.reads, m.writes} {
+		for _, t := range trackers {
+			for _, key := range t.hot(now, m.MinRate) {
+				if !seen[key] {
+					seen[key] = true
+					keys = append(keys, key)
 				}
 			}
-			_ = score // you could log or threshold
 		}
 	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Rates returns the estimated decayed read and write rates of key per
+// region, in operations per second. A DecisionFunc can call it to see the
+// same stats a Policy is given. Regions below MinRate are left out, as are
+// regions where key is not a heavy hitter or its count is within the
+// sketch's error.
+func (m *Manager) Rates(key string) KeyStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	s := KeyStats{Reads: make(map[string]float64), Writes: make(map[string]float64)}
+	for region, t := range m.reads {
+		if rate := t.rate(key, now); rate > 0 && rate >= m.MinRate {
+			s.Reads[region] = rate
+		}
+	}
+	for region, t := range m.writes {
+		if rate := t.rate(key, now); rate > 0 && rate >= m.MinRate {
+			s.Writes[region] = rate
+		}
+	}
+	return s
+}
+
+// target applies decisions to key's current replicas within the
+// replica-count bounds. Removals and additions are taken in descending score
+// order; current replicas keep their positions so the primary only changes
+// if it is removed or another node is promoted. A list already outside the
+// bounds is brought back: trimmed of its lowest-scored replicas, or topped
+// up from the key's ring preference list. It also returns notes on
+// decisions the bounds overruled and on these corrections.
+func (m *Manager) target(key string, current []string, decisions []Decision) ([]string, []string) {
+	var notes []string
+	var adds, removes, promotes []Decision
+	for _, d := range decisions {
+		switch {
+		case d.Action == Add && !contains(current, d.Node):
+			adds = append(adds, d)
+		case d.Action == Remove && contains(current, d.Node):
+			removes = append(removes, d)
+		case d.Action == Promote:
+			promotes = append(promotes, d)
+		}
+	}
+	byScore := func(ds []Decision) {
+		sort.SliceStable(ds, func(i, j int) bool { return ds[i].Score > ds[j].Score })
+	}
+	byScore(adds)
+	byScore(removes)
+
+	dropped := make(map[string]bool)
+	for _, d := range removes {
+		if len(current)-len(dropped) <= m.MinReplicas {
+			notes = append(notes, fmt.Sprintf("keeping %s: at MinReplicas=%d", d.Node, m.MinReplicas))
+			continue
+		}
+		dropped[d.Node] = true
+	}
+	next := make([]string, 0, len(current)+len(adds))
+	for _, node := range current {
+		if !dropped[node] {
+			next = append(next, node)
+		}
+	}
+	for _, d := range adds {
+		if m.MaxReplicas > 0 && len(next) >= m.MaxReplicas {
+			notes = append(notes, fmt.Sprintf("not adding %s: at MaxReplicas=%d", d.Node, m.MaxReplicas))
+			continue
+		}
+		if !contains(next, d.Node) {
+			next = append(next, d.Node)
+		}
+	}
+	if m.MaxReplicas > 0 && len(next) > m.MaxReplicas {
+		next, notes = m.trim(next, decisions, notes)
+	}
+	if len(next) < m.MinReplicas {
+		for _, node := range m.ring.Preference(key, m.MinReplicas) {
+			if len(next) >= m.MinReplicas {
+				break
+			}
+			if !contains(next, node) && !dropped[node] {
+				next = append(next, node)
+				notes = append(notes, fmt.Sprintf("adding %s: below MinReplicas=%d", node, m.MinReplicas))
+			}
+		}
+	}
+	byScore(promotes)
+	if len(promotes) > 0 {
+		d := promotes[0]
+		if i := indexOf(next, d.Node); i > 0 {
+			next = append(append([]string{d.Node}, next[:i]...), next[i+1:]...)
+		} else if i < 0 {
+			notes = append(notes, fmt.Sprintf("not promoting %s: not a replica", d.Node))
+		}
+	}
+	return next, notes
+}
+
+// trim drops replicas from next down to MaxReplicas, lowest-scored first:
+// replicas the policy wants removed, then those it has no opinion on, then
+// those it wants added, the later in the list the earlier within each group.
+// The primary is kept.
+func (m *Manager) trim(next []string, decisions []Decision, notes []string) ([]string, []string) {
+	score := make(map[string]float64, len(next))
+	for _, d := range decisions {
+		switch d.Action {
+		case Add, Promote:
+			score[d.Node] = math.Max(score[d.Node], d.Score)
+		case Remove:
+			score[d.Node] = -d.Score
+		}
+	}
+	order := append([]string(nil), next[1:]...)
+	sort.SliceStable(order, func(i, j int) bool {
+		if si, sj := score[order[i]], score[order[j]]; si != sj {
+			return si < sj
+		}
+		return indexOf(next, order[i]) > indexOf(next, order[j])
+	})
+	drop := make(map[string]bool)
+	for _, node := range order[:len(next)-m.MaxReplicas] {
+		drop[node] = true
+		notes = append(notes, fmt.Sprintf("removing %s: above MaxReplicas=%d", node, m.MaxReplicas))
+	}
+	kept := next[:0:0]
+	for _, node := range next {
+		if !drop[node] {
+			kept = append(kept, node)
+		}
+	}
+	return kept, notes
+}
+
+// advance implements the hysteresis: a change is only applied once the
+// same target has been proposed Confirmations times in a row and the key
+// has not changed within Cooldown.
+func (m *Manager) advance(key string, target []string) bool {
+	m.planMu.Lock()
+	defer m.planMu.Unlock()
+	p := m.pending[key]
+	if sameList(p.target, target) {
+		p.seen++
+	} else {
+		p = proposal{target: target, seen: 1}
+	}
+	m.pending[key] = p
+	if p.seen < m.Confirmations {
+		return false
+	}
+	return time.Since(m.lastChanged[key]) >= m.Cooldown
+}
+
+// indexOf returns the position of s in slice, or -1.
+func indexOf(slice []string, s string) int {
+	for i, v := range slice {
+		if v == s {
+			return i
+		}
+	}
+	return -1
 }
 
 // helper
@@ -0,0 +1,121 @@
+// internal/replication/manager_test.go
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/metadata"
+)
+
+// testRing returns a ring over n nodes "n0".."n<n-1>", with regions given
+// by region(i) if set.
+func testRing(t *testing.T, n int, region func(i int) string) *hashring.Ring {
+	t.Helper()
+	cfg := hashring.Config{VNodes: 50, Regions: make(map[string]string)}
+	for i := 0; i < n; i++ {
+		node := fmt.Sprintf("n%d", i)
+		cfg.Nodes = append(cfg.Nodes, node)
+		if region != nil {
+			cfg.Regions[node] = region(i)
+		}
+	}
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := hashring.New(cfg.VNodes)
+	r.Update(raw)
+	return r
+}
+
+func TestTargetRestoresBounds(t *testing.T) {
+	ring := testRing(t, 8, nil)
+	m := NewManager(ring, metadata.NewMemory(), 3, DecisionFunc(func(string, string) (bool, float64) { return false, 0 }))
+	m.MinReplicas, m.MaxReplicas = 3, 4
+
+	// above the maximum: the lowest-scored go, the primary stays
+	current := []string{"n0", "n1", "n2", "n3", "n4", "n5"}
+	decisions := []Decision{
+		{Node: "n1", Action: Remove, Score: 5},
+		{Node: "n5", Action: Add, Score: 10}, // already a replica: a vote to keep it
+	}
+	got, _ := m.target("k", current, decisions)
+	// n1 is removed by the policy; of the rest, n4 is trimmed from the end,
+	// n5 kept for its score
+	if want := []string{"n0", "n2", "n3", "n5"}; !sameList(got, want) {
+		t.Fatalf("target = %v, want %v", got, want)
+	}
+
+	// no decisions at all still trims
+	got, notes := m.target("k", current, nil)
+	if want := []string{"n0", "n1", "n2", "n3"}; !sameList(got, want) {
+		t.Fatalf("target = %v, want %v (notes %v)", got, want, notes)
+	}
+
+	// below the minimum: topped up from the ring's preference list
+	pref := ring.Preference("k", 3)
+	got, _ = m.target("k", pref[:1], nil)
+	if len(got) != 3 || got[0] != pref[0] {
+		t.Fatalf("target = %v, want 3 replicas led by %s", got, pref[0])
+	}
+	for _, node := range got {
+		if !contains(pref, node) {
+			t.Fatalf("target = %v adds %s, not in the preference list %v", got, node, pref)
+		}
+	}
+}
+
+func TestOperatorPlansOutliveRounds(t *testing.T) {
+	m := NewManager(testRing(t, 3, nil), metadata.NewMemory(), 3, DecisionFunc(func(string, string) (bool, float64) { return false, 0 }))
+	ctx := context.Background()
+	p := m.Plan(ctx)
+	for i := 0; i < 2*maxStoredPlans; i++ {
+		m.evaluateAllKeys(ctx)
+	}
+
+	if _, _, err := m.ApplyPlan(ctx, p.ID, nil); !errors.Is(err, ErrNotLeader) {
+		t.Fatalf("ApplyPlan on a standby = %v, want ErrNotLeader", err)
+	}
+	m.leading.Store(true)
+	if _, _, err := m.ApplyPlan(ctx, p.ID, nil); err != nil {
+		t.Fatalf("operator plan lost after placement rounds: %v", err)
+	}
+}
+
+func TestApplyPlanRefusesStalePlacement(t *testing.T) {
+	ring := testRing(t, 4, nil)
+	md := metadata.NewMemory()
+	m := NewManager(ring, md, 2, DecisionFunc(func(string, string) (bool, float64) { return false, 0 }))
+	m.leading.Store(true)
+	ctx := context.Background()
+	current := ring.GetReplicaList("k", 2)
+	m.plans["p"] = &Plan{ID: "p", Changes: []Change{{
+		Key:      "k",
+		Current:  current,
+		Proposed: current[:1],
+	}}}
+
+	// an operator moves the key after the plan was made
+	moved := minus(ring.AllNodes(), current)
+	if _, err := md.SetReplicas("k", moved, 0); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := json.Marshal(moved)
+	ring.UpdateReplicas("k", raw)
+
+	applied, failed, err := m.ApplyPlan(ctx, "p", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 0 || !errors.Is(failed["k"], ErrConflict) {
+		t.Fatalf("ApplyPlan = %v, %v; want k to fail with ErrConflict", applied, failed)
+	}
+	if repls, _, _ := md.Replicas("k"); !sameList(repls, moved) {
+		t.Fatalf("replicas of k = %v, want the operator's %v", repls, moved)
+	}
+}
@@ -3,48 +3,122 @@ package replication
 import (
 	"context"
 	"fmt"
+	"io"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/audit"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
 	"github.com/ksharma120497/adaptive-geo-distributed-database/proto"
 )
 
-// Migrate streams the current value of key from a source node to a destination.
-func Migrate(ctx context.Context, key, srcAddr, dstAddr string) error {
-	// 1) Dial source
-	srcConn, err := grpc.DialContext(ctx, srcAddr, grpc.WithInsecure(), grpc.WithBlock())
-	if err != nil {
-		return fmt.Errorf("dial source %s: %w", srcAddr, err)
-	}
-	defer srcConn.Close()
-	srcClient := proto.NewKVClient(srcConn)
+const (
+	// dialTimeout bounds connection setup so a dead node fails fast.
+	dialTimeout = 2 * time.Second
+	// transferAttempts is how often Transfer resumes an interrupted stream.
+	transferAttempts = 3
+	// transferChunkSize is the number of entries per transfer chunk.
+	transferChunkSize = 256
+)
 
-	// 2) Retrieve value
-	getCtx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+// TransferResult reports what a Transfer moved.
+type TransferResult struct {
+	Keys   int
+	Bytes  int
+	Cursor string // last key applied on the destination
+}
+
+// dial connects to a storage node, giving up after dialTimeout.
+func dial(ctx context.Context, addr string) (*grpc.ClientConn, error) {
+	dialCtx, cancel := context.WithTimeout(ctx, dialTimeout)
 	defer cancel()
-	resp, err := srcClient.Get(getCtx, &proto.GetRequest{Key: key})
+	return grpc.DialContext(dialCtx, addr, grpc.WithInsecure(), grpc.WithBlock())
+}
+
+// Transfer has dstAddr pull the selection in req from srcAddr over a single
+// server-to-server stream. An interrupted stream is resumed from the last
+// applied cursor; checksum mismatches are retried the same way.
+func Transfer(ctx context.Context, srcAddr, dstAddr string, req *proto.TransferRequest) (TransferResult, error) {
+	res := TransferResult{Cursor: req.Cursor}
+	conn, err := dial(ctx, dstAddr)
 	if err != nil {
-		return fmt.Errorf("get %s from %s: %w", key, srcAddr, err)
+		return res, fmt.Errorf("dial dest %s: %w", dstAddr, err)
 	}
-	if !resp.Found {
-		return fmt.Errorf("key %s not found on source %s", key, srcAddr)
+	defer conn.Close()
+	client := proto.NewKVClient(conn)
+
+	if req.ChunkSize == 0 {
+		req.ChunkSize = transferChunkSize
 	}
+	for attempt := 1; ; attempt++ {
+		req.Cursor = res.Cursor
+		err = importOnce(ctx, client, srcAddr, req, &res)
+		if err == nil {
+			return res, nil
+		}
+		if ctx.Err() != nil || attempt == transferAttempts || status.Code(err) == codes.Unimplemented {
+			return res, fmt.Errorf("transfer %s -> %s: %w", srcAddr, dstAddr, err)
+		}
+	}
+}
 
-	// 3) Dial destination
-	dstConn, err := grpc.DialContext(ctx, dstAddr, grpc.WithInsecure(), grpc.WithBlock())
+// importOnce runs one Import stream, accumulating its progress into res.
+func importOnce(ctx context.Context, client proto.KVClient, srcAddr string, req *proto.TransferRequest, res *TransferResult) error {
+	stream, err := client.Import(ctx, &proto.ImportRequest{Source: srcAddr, Transfer: req})
 	if err != nil {
-		return fmt.Errorf("dial dest %s: %w", dstAddr, err)
+		return err
 	}
-	defer dstConn.Close()
-	dstClient := proto.NewKVClient(dstConn)
-
-	// 4) Put value
-	putCtx, cancel2 := context.WithTimeout(ctx, 500*time.Millisecond)
-	defer cancel2()
-	if _, err := dstClient.Put(putCtx, &proto.PutRequest{Key: key, Value: resp.Value}); err != nil {
-		return fmt.Errorf("put %s to %s: %w", key, dstAddr, err)
+	var keys, bytes int
+	for {
+		p, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// progress counters restart with every stream
+		res.Keys += int(p.Keys) - keys
+		res.Bytes += int(p.Bytes) - bytes
+		keys, bytes = int(p.Keys), int(p.Bytes)
+		res.Cursor = p.Cursor
 	}
+}
 
+// Migrate streams the current value of key from a source node to a
+// destination. It refuses, and audits, destinations the key's residency
+// rules in r forbid.
+func Migrate(ctx context.Context, r *hashring.Ring, key, srcAddr, dstAddr string) error {
+	if err := r.CheckResidency(key, []string{dstAddr}); err != nil {
+		audit.Record("migrate", "residency_violation", err)
+		return err
+	}
+	res, err := Transfer(ctx, srcAddr, dstAddr, &proto.TransferRequest{Keys: []string{key}})
+	if err != nil {
+		return err
+	}
+	if res.Keys == 0 {
+		return fmt.Errorf("key %s not found on source %s", key, srcAddr)
+	}
 	return nil
 }
+
+// MigrateRanges copies every key whose ring hash lies in one of ranges from a
+// source node to a destination, limited to bytesPerSec (zero is unlimited).
+// Keys with a prefix in exclude are skipped.
+func MigrateRanges(ctx context.Context, ranges []hashring.Range, srcAddr, dstAddr string, bytesPerSec float64, exclude []string) (TransferResult, error) {
+	return migrateRanges(ctx, ranges, "", srcAddr, dstAddr, bytesPerSec, exclude, 0)
+}
+
+// migrateRanges is MigrateRanges restricted to keys with prefix and entries
+// of at least minVersion.
+func migrateRanges(ctx context.Context, ranges []hashring.Range, prefix, srcAddr, dstAddr string, bytesPerSec float64, exclude []string, minVersion uint64) (TransferResult, error) {
+	req := &proto.TransferRequest{BytesPerSec: uint64(bytesPerSec), MinVersion: minVersion, ExcludePrefixes: exclude, Prefix: prefix}
+	for _, r := range ranges {
+		req.Ranges = append(req.Ranges, &proto.HashRange{Start: r.Start, End: r.End})
+	}
+	return Transfer(ctx, srcAddr, dstAddr, req)
+}
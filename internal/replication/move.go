@@ -0,0 +1,261 @@
+package replication
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/audit"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/metadata"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/proto"
+)
+
+// ErrConflict is returned when /replicas/<key> changed underneath a move.
+var ErrConflict = errors.New("replica list changed concurrently")
+
+// Mover relocates a key between replica sets without losing writes:
+//
+//  1. publish the union of old and new replicas, so proxies write to both;
+//  2. wait Settle for proxies to pick up the union and in-flight writes to
+//     finish;
+//  3. copy the key from the old primary to every new replica (versioned, so a
+//     copy never overwrites a newer dual-written value);
+//  4. verify version and content on every new replica;
+//  5. compare-and-swap /replicas/<key> to the new list;
+//  6. after Grace, delete the key from replicas that were dropped.
+type Mover struct {
+	ring *hashring.Ring
+	md   metadata.Store
+	R    int // replicas of keys without an override
+
+	// Settle is how long proxies get to observe the dual-write list.
+	Settle time.Duration
+	// Grace is how long dropped replicas keep their copy after the flip,
+	// so proxies still routing with the old list can read it.
+	Grace time.Duration
+	// Throttle, if set, is charged with the bytes of every copy between
+	// two nodes and may block to pace the moves that follow.
+	Throttle func(ctx context.Context, nodes []string, bytes int) error
+}
+
+// NewMover constructs a Mover with default settle and grace periods, for
+// keys placed on R replicas unless overridden.
+func NewMover(r *hashring.Ring, md metadata.Store, R int) *Mover {
+	return &Mover{
+		ring:   r,
+		md:     md,
+		R:      R,
+		Settle: 2 * time.Second,
+		Grace:  30 * time.Second,
+	}
+}
+
+// Move relocates key from replica list from to replica list to. Nodes are
+// addressed by their ring IDs. It fails with ErrConflict unless from is
+// still the key's replica list, so a stale plan cannot overwrite a newer
+// placement. On failure before the flip, routing is restored to from. Moves
+// to nodes the key's residency rules forbid are refused and audited.
+func (m *Mover) Move(ctx context.Context, key string, from, to []string) error {
+	if err := m.ring.CheckResidency(key, to); err != nil {
+		audit.Record("mover", "residency_violation", err)
+		return fmt.Errorf("move %s: %w", key, err)
+	}
+	rev, err := m.current(key, from)
+	if err != nil {
+		return err
+	}
+	added, removed := minus(to, from), minus(from, to)
+	if len(added) == 0 {
+		// nothing to copy: a reorder or shrink is a plain flip
+		if _, err := m.swap(key, rev, to); err != nil {
+			return err
+		}
+		m.dropLater(key, to, removed)
+		return nil
+	}
+	if len(from) == 0 {
+		return fmt.Errorf("move %s: no current replicas to copy from", key)
+	}
+
+	// 1-2) dual-write phase
+	union := append(append([]string(nil), from...), added...)
+	rev, err = m.swap(key, rev, union)
+	if err != nil {
+		return err
+	}
+	if err := sleep(ctx, m.Settle); err != nil {
+		m.restore(key, rev, from)
+		return err
+	}
+
+	// 3-4) copy and verify
+	if err := m.copyAndVerify(ctx, key, from[0], added); err != nil {
+		m.restore(key, rev, from)
+		return fmt.Errorf("move %s: %w", key, err)
+	}
+
+	// 5) flip routing
+	if _, err := m.swap(key, rev, to); err != nil {
+		return err
+	}
+
+	// 6) drop source copies
+	m.dropLater(key, to, removed)
+	return nil
+}
+
+// copyAndVerify copies key from src to every node in dsts and checks that
+// each now holds at least the source's version with identical content.
+func (m *Mover) copyAndVerify(ctx context.Context, key, src string, dsts []string) error {
+	for _, dst := range dsts {
+		res, err := Transfer(ctx, src, dst, &proto.TransferRequest{Keys: []string{key}})
+		if err != nil {
+			return err
+		}
+		if m.Throttle != nil {
+			if err := m.Throttle(ctx, []string{src, dst}, res.Bytes); err != nil {
+				return err
+			}
+		}
+	}
+	want, err := getVersion(ctx, src, key)
+	if err != nil {
+		return err
+	}
+	if !want.Found {
+		// nothing stored yet; dual writes keep the new replicas current
+		return nil
+	}
+	for _, dst := range dsts {
+		got, err := getVersion(ctx, dst, key)
+		if err != nil {
+			return err
+		}
+		switch {
+		case !got.Found || got.Version < want.Version:
+			return fmt.Errorf("verify %s: version %d, want >= %d", dst, got.Version, want.Version)
+		case got.Version == want.Version && !bytes.Equal(got.Value, want.Value):
+			return fmt.Errorf("verify %s: content differs at version %d", dst, got.Version)
+		}
+	}
+	return nil
+}
+
+// current returns the revision of key's stored override, failing with
+// ErrConflict if it is not the override routing is based on, or if the
+// key's replicas are no longer from.
+func (m *Mover) current(key string, from []string) (int64, error) {
+	stored, rev, err := m.md.Replicas(key)
+	if err != nil {
+		return 0, fmt.Errorf("read replicas of %s: %w", key, err)
+	}
+	if routed, _ := m.ring.Override(key); !sameList(stored, routed) {
+		return 0, fmt.Errorf("update replicas of %s: %w", key, ErrConflict)
+	}
+	if cur := m.ring.GetReplicaList(key, m.R); !sameList(cur, from) {
+		return 0, fmt.Errorf("update replicas of %s: now %v, not %v: %w", key, cur, from, ErrConflict)
+	}
+	return rev, nil
+}
+
+// swap replaces the key's replica list if it is still at revision rev and
+// returns the new revision.
+func (m *Mover) swap(key string, rev int64, next []string) (int64, error) {
+	rev, err := m.md.SetReplicas(key, next, rev)
+	if errors.Is(err, metadata.ErrConflict) {
+		return 0, fmt.Errorf("update replicas of %s: %w", key, ErrConflict)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("update replicas of %s: %w", key, err)
+	}
+	return rev, nil
+}
+
+// restore rolls routing back from the dual-write list, written at rev,
+// after a failed move.
+func (m *Mover) restore(key string, rev int64, from []string) {
+	if _, err := m.swap(key, rev, from); err != nil {
+		log.Printf("move rollback error for %s: %v", key, err)
+	}
+}
+
+// dropLater deletes key from the removed nodes once the grace period has
+// passed. A removed node's copy is only deleted if it is not newer than what
+// the new primary holds, so a misrouted late write is kept for inspection
+// rather than silently lost.
+func (m *Mover) dropLater(key string, to, removed []string) {
+	if len(removed) == 0 || len(to) == 0 {
+		return
+	}
+	time.AfterFunc(m.Grace, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		cur, err := getVersion(ctx, to[0], key)
+		if err != nil {
+			log.Printf("drop %s: read %s: %v", key, to[0], err)
+			return
+		}
+		if !cur.Found {
+			log.Printf("drop %s: not found on new primary %s, keeping old copies", key, to[0])
+			return
+		}
+		for _, node := range removed {
+			if err := deleteKey(ctx, node, key, cur.Version); err != nil {
+				log.Printf("drop %s from %s: %v", key, node, err)
+			}
+		}
+	})
+}
+
+// getVersion reads key with its version from a single node.
+func getVersion(ctx context.Context, addr, key string) (*proto.GetReply, error) {
+	conn, err := dial(ctx, addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", addr, err)
+	}
+	defer conn.Close()
+	resp, err := proto.NewKVClient(conn).Get(ctx, &proto.GetRequest{Key: key})
+	if err != nil {
+		return nil, fmt.Errorf("get %s from %s: %w", key, addr, err)
+	}
+	return resp, nil
+}
+
+// deleteKey removes key from a node unless it holds a version newer than
+// version.
+func deleteKey(ctx context.Context, addr, key string, version uint64) error {
+	conn, err := dial(ctx, addr)
+	if err != nil {
+		return fmt.Errorf("dial %s: %w", addr, err)
+	}
+	defer conn.Close()
+	_, err = proto.NewKVClient(conn).Delete(ctx, &proto.DeleteRequest{Key: key, Version: version})
+	return err
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// minus returns the elements of a that are not in b.
+func minus(a, b []string) []string {
+	var out []string
+	for _, v := range a {
+		if !contains(b, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,203 @@
+package replication
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Change is one proposed replica-list change, with everything an operator
+// needs to judge it.
+type Change struct {
+	Key      string     `json:"key"`
+	Current  []string   `json:"current"`
+	Proposed []string   `json:"proposed"`
+	Stats    KeyStats   `json:"stats"`
+	Decision []Decision `json:"decisions"`
+	// Latency and cost deltas are only set when the policy is an Estimator.
+	LatencyBeforeMs float64 `json:"latency_before_ms,omitempty"`
+	LatencyAfterMs  float64 `json:"latency_after_ms,omitempty"`
+	CostBefore      float64 `json:"cost_before,omitempty"`
+	CostAfter       float64 `json:"cost_after,omitempty"`
+	// Reasons holds the manager's own notes, e.g. why the change is held
+	// back by the hysteresis or clipped by the replica bounds.
+	Reasons []string `json:"reasons,omitempty"`
+	// Priority orders changes when the change budget or move limits apply:
+	// the sum of the scores of the decisions the change carries out.
+	Priority float64 `json:"priority"`
+	// Ready is true when the hysteresis would let the change through now.
+	Ready bool `json:"ready"`
+}
+
+// Plan is a reviewable set of changes. Applying a plan re-checks every
+// change's current replica list, so a stale plan cannot clobber newer
+// placements.
+type Plan struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Changes   []Change  `json:"changes"`
+}
+
+// maxStoredPlans bounds how many operator plans are kept for approval.
+const maxStoredPlans = 16
+
+// ErrNotLeader is returned by ApplyPlan on a manager instance that does not
+// hold the leadership, which another instance may be exercising.
+var ErrNotLeader = errors.New("not the leading placement manager")
+
+// Plan evaluates every hot key without changing anything and returns the
+// proposed changes. The plan is kept so it can be approved with ApplyPlan.
+func (m *Manager) Plan(ctx context.Context) *Plan {
+	p := m.plan(ctx)
+	m.planMu.Lock()
+	defer m.planMu.Unlock()
+	m.plans[p.ID] = p
+	m.planOrder = append(m.planOrder, p.ID)
+	if len(m.planOrder) > maxStoredPlans {
+		delete(m.plans, m.planOrder[0])
+		m.planOrder = m.planOrder[1:]
+	}
+	return p
+}
+
+// plan is Plan without keeping the plan, as the placement loop plans every
+// round and would otherwise evict the plans operators asked for.
+func (m *Manager) plan(ctx context.Context) *Plan {
+	p := &Plan{ID: newPlanID(), CreatedAt: time.Now().UTC(), Changes: []Change{}}
+	for _, key := range m.hotKeys() {
+		if ctx.Err() != nil {
+			break
+		}
+		// placement policies limit where a key may go
+		if ch, ok := m.propose(key, m.ring.Candidates(key)); ok {
+			p.Changes = append(p.Changes, ch)
+		}
+	}
+	return p
+}
+
+// propose runs the policy for one key. It reports false if the key's
+// replicas would stay as they are.
+func (m *Manager) propose(key string, candidates []string) (Change, bool) {
+	stats := m.Rates(key)
+	current := m.ring.GetReplicaList(key, m.R)
+	decisions := m.policy.Decide(key, current, candidates, stats)
+	if d, ok := m.promotion(key, current, decisions, stats); ok {
+		decisions = append(decisions, d)
+	}
+	target, notes := m.target(key, current, decisions)
+	if sameList(current, target) {
+		return Change{}, false
+	}
+	ch := Change{
+		Key:      key,
+		Current:  current,
+		Proposed: target,
+		Stats:    stats,
+		Decision: decisions,
+		Reasons:  notes,
+		Priority: priority(current, target, decisions),
+	}
+	if est, ok := m.policy.(Estimator); ok {
+		ch.LatencyBeforeMs, ch.CostBefore = est.Estimate(current, stats)
+		ch.LatencyAfterMs, ch.CostAfter = est.Estimate(target, stats)
+	}
+	ch.Ready, ch.Reasons = m.readiness(key, target, ch.Reasons)
+	return ch, true
+}
+
+// readiness explains where a proposed target stands in the hysteresis,
+// without advancing it.
+func (m *Manager) readiness(key string, target []string, reasons []string) (bool, []string) {
+	m.planMu.Lock()
+	p := m.pending[key]
+	last, changed := m.lastChanged[key]
+	m.planMu.Unlock()
+
+	seen := 1
+	if sameList(p.target, target) {
+		seen = p.seen + 1
+	}
+	ready := true
+	if seen < m.Confirmations {
+		ready = false
+		reasons = append(reasons, fmt.Sprintf("proposed %d of %d consecutive rounds", seen, m.Confirmations))
+	}
+	if changed && time.Since(last) < m.Cooldown {
+		ready = false
+		reasons = append(reasons, fmt.Sprintf("cooling down until %s", last.Add(m.Cooldown).UTC().Format(time.RFC3339)))
+	}
+	return ready, reasons
+}
+
+// ApplyPlan applies an approved plan, or only the listed keys of it. The
+// hysteresis is bypassed since an operator approved the changes, but each
+// move still fails if the key's replicas changed since planning. It returns
+// the applied keys and the errors of the failed ones. Only the leader
+// applies plans; other instances return ErrNotLeader.
+func (m *Manager) ApplyPlan(ctx context.Context, id string, keys []string) (applied []string, failed map[string]error, err error) {
+	if !m.leading.Load() {
+		return nil, nil, ErrNotLeader
+	}
+	m.planMu.Lock()
+	p, ok := m.plans[id]
+	m.planMu.Unlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown plan %q", id)
+	}
+	var approved []Change
+	for _, ch := range p.Changes {
+		if len(keys) == 0 || contains(keys, ch.Key) {
+			approved = append(approved, ch)
+		}
+	}
+	// the approval is the budget, but the move limits still hold
+	applied, failed, _ = m.applyAll(ctx, approved, 0)
+	return applied, failed, nil
+}
+
+// apply moves a key to its proposed replicas and records the change for
+// the cooldown.
+func (m *Manager) apply(ctx context.Context, ch Change) error {
+	// the mover copies data to added nodes and deletes it from dropped
+	// ones after its grace period
+	if err := m.mover.Move(ctx, ch.Key, ch.Current, ch.Proposed); err != nil {
+		return err
+	}
+	m.planMu.Lock()
+	m.lastChanged[ch.Key] = time.Now()
+	if len(ch.Current) > 0 && len(ch.Proposed) > 0 && ch.Current[0] != ch.Proposed[0] {
+		m.lastPrimary[ch.Key] = time.Now()
+	}
+	delete(m.pending, ch.Key)
+	m.planMu.Unlock()
+	return nil
+}
+
+// priority sums the scores of the decisions that turn current into target.
+func priority(current, target []string, decisions []Decision) float64 {
+	var sum float64
+	for _, d := range decisions {
+		switch {
+		case d.Action == Add && contains(target, d.Node) && !contains(current, d.Node),
+			d.Action == Remove && contains(current, d.Node) && !contains(target, d.Node),
+			d.Action == Promote && len(target) > 0 && target[0] == d.Node && (len(current) == 0 || current[0] != d.Node):
+			sum += d.Score
+		}
+	}
+	return sum
+}
+
+func newPlanID() string {
+	var b [6]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func sameList(a, b []string) bool {
+	return strings.Join(a, ",") == strings.Join(b, ",")
+}
@@ -0,0 +1,83 @@
+package replication
+
+// Action is what a Policy wants done with one node of a key's replica list.
+type Action int
+
+const (
+	// Keep leaves the node as it is (in or out of the list).
+	Keep Action = iota
+	// Add makes the node a replica of the key.
+	Add
+	// Remove drops the node from the key's replicas.
+	Remove
+	// Promote makes the node, which must end up a replica, the primary
+	// that serves reads.
+	Promote
+)
+
+// MarshalText encodes the action by name in JSON plans.
+func (a Action) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a Action) String() string {
+	switch a {
+	case Add:
+		return "add"
+	case Remove:
+		return "remove"
+	case Promote:
+		return "promote"
+	default:
+		return "keep"
+	}
+}
+
+// Decision is a Policy's verdict for one node. Higher scores win when the
+// manager has to choose between decisions to respect replica-count bounds.
+type Decision struct {
+	Node   string  `json:"node"`
+	Action Action  `json:"action"`
+	Score  float64 `json:"score"`
+	// Reason explains the decision to operators reviewing a plan.
+	Reason string `json:"reason,omitempty"`
+}
+
+// KeyStats is the access profile of a key: exponentially decayed operations
+// per second, per client region.
+type KeyStats struct {
+	Reads  map[string]float64 `json:"reads"`
+	Writes map[string]float64 `json:"writes"`
+}
+
+// Policy is the cost model: given a key's current replicas, the candidate
+// nodes and the key's access stats, decide which nodes to add or remove.
+// Nodes without a decision are kept as they are.
+type Policy interface {
+	Decide(key string, current, candidates []string, stats KeyStats) []Decision
+}
+
+// Estimator is implemented by policies that can price a replica list. The
+// planner uses it to report expected latency and cost deltas.
+type Estimator interface {
+	Estimate(nodes []string, stats KeyStats) (latencyMs, cost float64)
+}
+
+// DecisionFunc is your cost model: for key x and node y, should we add/remove?
+// As a Policy it only ever adds replicas.
+type DecisionFunc func(key, node string) (shouldAdd bool, score float64)
+
+// Decide implements Policy by asking f about every candidate that is not yet
+// a replica.
+func (f DecisionFunc) Decide(key string, current, candidates []string, _ KeyStats) []Decision {
+	var out []Decision
+	for _, node := range candidates {
+		if contains(current, node) {
+			continue
+		}
+		if add, score := f(key, node); add {
+			out = append(out, Decision{Node: node, Action: Add, Score: score, Reason: "decision function"})
+		}
+	}
+	return out
+}
@@ -0,0 +1,60 @@
+package replication
+
+import (
+	"fmt"
+	"time"
+)
+
+// promotion returns a Promote decision moving key's primary to the region
+// generating the most traffic, if that region out-draws the current
+// primary's by PrimaryMargin and the primary has not moved within
+// PrimaryCooldown. The promoted node must be a replica or being added by
+// decisions.
+func (m *Manager) promotion(key string, current []string, decisions []Decision, stats KeyStats) (Decision, bool) {
+	if m.PrimaryMargin <= 0 || len(current) == 0 {
+		return Decision{}, false
+	}
+	m.planMu.Lock()
+	last, moved := m.lastPrimary[key]
+	m.planMu.Unlock()
+	if moved && time.Since(last) < m.PrimaryCooldown {
+		return Decision{}, false
+	}
+
+	traffic := make(map[string]float64)
+	for region, rate := range stats.Reads {
+		traffic[region] += rate
+	}
+	for region, rate := range stats.Writes {
+		traffic[region] += rate
+	}
+	var top string
+	for region, rate := range traffic {
+		if top == "" || rate > traffic[top] || rate == traffic[top] && region < top {
+			top = region
+		}
+	}
+	primaryRegion := m.ring.Region(current[0])
+	if top == "" || top == primaryRegion || traffic[top] < m.PrimaryMargin*traffic[primaryRegion] {
+		return Decision{}, false
+	}
+
+	nodes := append([]string(nil), current...)
+	for _, d := range decisions {
+		if d.Action == Add {
+			nodes = append(nodes, d.Node)
+		}
+	}
+	for _, node := range nodes {
+		if m.ring.Region(node) == top {
+			return Decision{
+				Node:   node,
+				Action: Promote,
+				Score:  traffic[top] - traffic[primaryRegion],
+				Reason: fmt.Sprintf("primary follows the workload: %s generates %.3g ops/s, %s %.3g",
+					top, traffic[top], primaryRegion, traffic[primaryRegion]),
+			}, true
+		}
+	}
+	return Decision{}, false
+}
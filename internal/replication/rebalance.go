@@ -0,0 +1,313 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/metadata"
+)
+
+// Progress reports how far a rebalance has got.
+type Progress struct {
+	Ranges        int // ranges whose replica set changes
+	Transfers     int // source/destination streams needed to copy them
+	TransfersDone int
+	Keys          int // keys copied so far
+	Bytes         int // key+value bytes copied so far
+}
+
+// Rebalancer moves data ahead of ring configuration changes: it diffs the
+// current ring against a proposed configuration, copies every affected range
+// from its old owners to its new ones, and only then publishes the
+// configuration so routing switches to owners that already hold the data.
+// Writes that reach the old owners between the copy and the switch are
+// picked up by catch-up passes that re-copy entries written since the copy
+// started: one right after the switch, and one after Settle, once every
+// proxy routes by the new configuration and the old owners fence writes
+// still sent with the old one.
+type Rebalancer struct {
+	ring        *hashring.Ring
+	md          metadata.Store
+	R           int
+	bytesPerSec float64
+
+	// Settle is how long proxies and servers get to pick up a published
+	// configuration before the final catch-up.
+	Settle time.Duration
+	// MaxClockSkew bounds how far the clocks stamping write versions may
+	// lag the rebalancer's; catch-ups copy from that much before the copy
+	// started.
+	MaxClockSkew time.Duration
+	// OnProgress, if set, is called after every completed transfer.
+	OnProgress func(Progress)
+
+	watchOnce sync.Once
+	proposals chan []byte // the latest proposal not yet taken by Run
+}
+
+// NewRebalancer constructs a rebalancer. bytesPerSec throttles the copy
+// traffic; zero means unlimited.
+func NewRebalancer(r *hashring.Ring, md metadata.Store, R int, bytesPerSec float64) *Rebalancer {
+	return &Rebalancer{
+		ring:         r,
+		md:           md,
+		R:            R,
+		bytesPerSec:  bytesPerSec,
+		Settle:       2 * time.Second,
+		MaxClockSkew: time.Second,
+		proposals:    make(chan []byte, 1),
+	}
+}
+
+// Failed proposals are retried after retryMin, doubling up to retryMax.
+const (
+	retryMin = time.Second
+	retryMax = time.Minute
+)
+
+// Run applies every configuration staged under /ring/proposed until ctx is
+// done. Proposals are applied one at a time; a proposal that arrives while
+// another is being applied replaces any still waiting. A proposal that fails
+// is retried with backoff until it succeeds or a newer one replaces it.
+// Run may be called again, e.g. on every leadership term; a proposal left
+// unapplied is taken up by the next call.
+func (b *Rebalancer) Run(ctx context.Context) {
+	b.watchOnce.Do(func() {
+		b.md.WatchProposedRingConfig(b.stage)
+	})
+	retries := make(chan []byte, 1)
+	var (
+		pending []byte // the latest proposal, until it is applied
+		delay   = retryMin
+	)
+	defer func() {
+		if pending != nil {
+			b.requeue(pending)
+		}
+	}()
+	for {
+		var raw []byte
+		select {
+		case <-ctx.Done():
+			return
+		case raw = <-b.proposals:
+			if string(raw) != string(pending) {
+				delay = retryMin
+			}
+			pending = raw
+		case raw = <-retries:
+			if string(raw) != string(pending) {
+				// superseded while waiting
+				continue
+			}
+		}
+		err := b.Apply(ctx, raw)
+		if err == nil {
+			pending, delay = nil, retryMin
+			continue
+		}
+		log.Printf("rebalance error: %v (retrying in %v)", err, delay)
+		// a conflict was planned against an outdated ring and succeeds once
+		// routing has caught up; other failures, like an unreachable
+		// source, may be transient too
+		time.AfterFunc(delay, func() {
+			select {
+			case retries <- raw:
+			default:
+			}
+		})
+		delay = min(2*delay, retryMax)
+	}
+}
+
+// stage hands a new proposal to Run, replacing any still waiting.
+func (b *Rebalancer) stage(config []byte) {
+	for {
+		select {
+		case b.proposals <- config:
+			return
+		default:
+		}
+		select {
+		case <-b.proposals:
+		default:
+		}
+	}
+}
+
+// requeue hands an unapplied proposal back for the next Run, unless a
+// newer one is already waiting.
+func (b *Rebalancer) requeue(config []byte) {
+	select {
+	case b.proposals <- config:
+	default:
+	}
+}
+
+// Apply copies the data affected by the JSON-encoded ring configuration raw
+// and then publishes it as /ring/config. If any range fails to copy, routing
+// is left untouched and the error is returned. So is a metadata.ErrConflict
+// if /ring/config changed meanwhile, as the copies were planned against the
+// configuration it replaced.
+func (b *Rebalancer) Apply(ctx context.Context, raw []byte) error {
+	rev, err := b.currentConfig()
+	if err != nil {
+		return err
+	}
+	cfg, changes, err := b.plan(raw)
+	if err != nil {
+		return err
+	}
+
+	batches := batchChanges(changes)
+	// keys that must stay in their regions are not copied elsewhere
+	for _, bt := range batches {
+		bt.exclude = append(bt.exclude, b.ring.ResidencyExclusions(cfg.Regions[bt.dst])...)
+	}
+	p := Progress{Ranges: len(changes), Transfers: len(batches)}
+	copyStart := uint64(time.Now().Add(-b.MaxClockSkew).UnixNano())
+	for _, bt := range batches {
+		res, err := migrateRanges(ctx, bt.ranges, bt.prefix, bt.src, bt.dst, b.bytesPerSec, bt.exclude, 0)
+		if err != nil {
+			// the preferred source failed; retry range by range from the
+			// remaining old owners
+			res, err = b.copyFromAlternates(ctx, bt)
+		}
+		p.Keys += res.Keys
+		p.Bytes += res.Bytes
+		if err != nil {
+			return fmt.Errorf("copy to %s: %w", bt.dst, err)
+		}
+		p.TransfersDone++
+		if b.OnProgress != nil {
+			b.OnProgress(p)
+		}
+	}
+	if _, err := b.md.SetRingConfig(raw, rev); err != nil {
+		return fmt.Errorf("publish ring config: %w", err)
+	}
+
+	// catch-up: versions are write timestamps, so anything written since
+	// copyStart may have missed the bulk copy. Proxies still routing by the
+	// old configuration keep writing to the old owners until they see the
+	// new one, so the last pass runs once they have.
+	b.catchUp(ctx, batches, copyStart)
+	if err := sleep(ctx, b.Settle); err != nil {
+		return fmt.Errorf("final catch-up: %w", err)
+	}
+	b.catchUp(ctx, batches, copyStart)
+	return nil
+}
+
+// catchUp re-copies every batch's entries written since version since.
+func (b *Rebalancer) catchUp(ctx context.Context, batches []*batch, since uint64) {
+	for _, bt := range batches {
+		if _, err := migrateRanges(ctx, bt.ranges, bt.prefix, bt.src, bt.dst, b.bytesPerSec, bt.exclude, since); err != nil {
+			log.Printf("rebalance catch-up %s -> %s: %v", bt.src, bt.dst, err)
+		}
+	}
+}
+
+// currentConfig returns the revision of the stored /ring/config, failing
+// with metadata.ErrConflict while the ring has yet to apply it.
+func (b *Rebalancer) currentConfig() (int64, error) {
+	stored, rev, err := b.md.RingConfig()
+	if err != nil {
+		return 0, fmt.Errorf("read ring config: %w", err)
+	}
+	var cfg hashring.Config
+	if len(stored) > 0 {
+		if err := json.Unmarshal(stored, &cfg); err != nil {
+			return 0, fmt.Errorf("decode ring config: %w", err)
+		}
+	}
+	if cfg.Epoch != b.ring.Epoch() {
+		return 0, fmt.Errorf("ring at epoch %d, stored config at %d: %w", b.ring.Epoch(), cfg.Epoch, metadata.ErrConflict)
+	}
+	return rev, nil
+}
+
+// Plan returns the ranges whose owners change if raw were applied.
+func (b *Rebalancer) Plan(raw []byte) ([]hashring.RangeChange, error) {
+	_, changes, err := b.plan(raw)
+	return changes, err
+}
+
+// plan is Plan, also returning the decoded configuration. Owners are
+// resolved under the ring's placement policies and residency rules, as
+// routing resolves them.
+func (b *Rebalancer) plan(raw []byte) (hashring.Config, []hashring.RangeChange, error) {
+	var cfg hashring.Config
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return cfg, nil, fmt.Errorf("decode ring config: %w", err)
+	}
+	if cfg.VNodes == 0 {
+		cfg.VNodes = b.ring.Config().VNodes
+	}
+	changes, err := b.ring.DiffConfig(cfg, b.R)
+	return cfg, changes, err
+}
+
+// batch is the set of ranges one destination copies from one source, for
+// the keys with prefix.
+type batch struct {
+	src, dst string
+	prefix   string
+	ranges   []hashring.Range
+	changes  []hashring.RangeChange
+	exclude  []string // key prefixes outside the batch or dst may not hold
+}
+
+// batchChanges groups the ranges each new owner needs by preferred source,
+// the range's old primary, and key prefix, so each needs only one transfer
+// stream.
+// Ranges without previous owners (e.g. the very first configuration) hold
+// no data and are skipped.
+func batchChanges(changes []hashring.RangeChange) []*batch {
+	var order []*batch
+	byPair := make(map[[3]string]*batch)
+	for _, ch := range changes {
+		if len(ch.From) == 0 {
+			continue
+		}
+		for _, dst := range ch.Added {
+			pair := [3]string{ch.From[0], dst, ch.Prefix}
+			bt, ok := byPair[pair]
+			if !ok {
+				bt = &batch{src: ch.From[0], dst: dst, prefix: ch.Prefix, exclude: append([]string(nil), ch.Exclude...)}
+				byPair[pair] = bt
+				order = append(order, bt)
+			}
+			bt.ranges = append(bt.ranges, ch.Range)
+			bt.changes = append(bt.changes, ch)
+		}
+	}
+	return order
+}
+
+// copyFromAlternates copies each range of bt from the first of its other old
+// owners that answers. Every old owner holds the whole range.
+func (b *Rebalancer) copyFromAlternates(ctx context.Context, bt *batch) (TransferResult, error) {
+	var total TransferResult
+	for _, ch := range bt.changes {
+		err := fmt.Errorf("no reachable owner for %v", ch.Range)
+		for _, src := range ch.From[1:] {
+			var res TransferResult
+			res, err = migrateRanges(ctx, []hashring.Range{ch.Range}, bt.prefix, src, bt.dst, b.bytesPerSec, bt.exclude, 0)
+			total.Keys += res.Keys
+			total.Bytes += res.Bytes
+			if err == nil || ctx.Err() != nil {
+				break
+			}
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
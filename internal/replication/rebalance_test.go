@@ -0,0 +1,107 @@
+// internal/replication/rebalance_test.go
+package replication
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/hashring"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/kvstore"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/metadata"
+	"github.com/ksharma120497/adaptive-geo-distributed-database/proto"
+)
+
+// startStore serves a fresh store on a free port and returns its address.
+func startStore(t *testing.T) (string, *kvstore.KVStore) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := kvstore.NewWALStore(filepath.Join(t.TempDir(), "wal.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	proto.RegisterKVServer(srv, kvstore.NewService(store))
+	go srv.Serve(lis)
+	t.Cleanup(func() {
+		srv.Stop()
+		store.Close()
+	})
+	return lis.Addr().String(), store
+}
+
+func TestRebalanceKeepsDeletesMadeDuringCopy(t *testing.T) {
+	a, src := startStore(t)
+	b, dst := startStore(t)
+	md := metadata.NewMemory()
+	ring := hashring.New(20)
+	md.WatchRingConfig(ring.Update)
+	if _, err := md.SetRingConfig([]byte(`{"vnodes_per_node": 20, "nodes": ["`+a+`"]}`), metadata.AnyRevision); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); ring.Epoch() != 1; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("ring did not load its configuration")
+		}
+	}
+
+	raw := []byte(`{"vnodes_per_node": 20, "nodes": ["` + a + `", "` + b + `"]}`)
+	next := hashring.New(20)
+	next.Update(raw)
+	var moved []string
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("k%d", i)
+		src.Put(key, []byte("v"), uint64(i+1))
+		if next.GetReplicaList(key, 1)[0] == b {
+			moved = append(moved, key)
+		}
+	}
+	if len(moved) < 2 {
+		t.Fatalf("only %d keys move to the new node", len(moved))
+	}
+
+	rb := NewRebalancer(ring, md, 1, 0)
+	rb.Settle = 10 * time.Millisecond
+	// the delete lands on the old owner after the bulk copy
+	deleted := moved[0]
+	rb.OnProgress = func(Progress) {
+		src.Delete(deleted, uint64(time.Now().UnixNano()))
+	}
+	if err := rb.Apply(context.Background(), raw); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, ok := dst.Get(deleted); ok {
+		t.Fatalf("key deleted during the copy is back on the new owner: %q", v)
+	}
+	if _, ok := dst.Get(moved[1]); !ok {
+		t.Fatalf("key %s was not copied to the new owner", moved[1])
+	}
+}
+
+func TestRebalancerRunWatchesOnce(t *testing.T) {
+	md := metadata.NewMemory()
+	rb := NewRebalancer(hashring.New(20), md, 1, 0)
+	// one leadership term after another
+	for term := 0; term < 3; term++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			rb.Run(ctx)
+			close(done)
+		}()
+		cancel()
+		<-done
+	}
+	if n := len(md.Watches()); n != 1 {
+		t.Fatalf("%d watches after three terms, want 1", n)
+	}
+}
@@ -0,0 +1,141 @@
+package replication
+
+import (
+	"container/heap"
+	"hash/fnv"
+	"math"
+)
+
+// countMin is a count-min sketch with float counters. Estimates never
+// undercount; with width w and depth d they overcount by at most e/w of the
+// total with probability 1-exp(-d).
+type countMin struct {
+	width    int
+	counters [][]float64
+	total    float64
+}
+
+func newCountMin(width, depth int) *countMin {
+	c := &countMin{width: width, counters: make([][]float64, depth)}
+	for i := range c.counters {
+		c.counters[i] = make([]float64, width)
+	}
+	return c
+}
+
+// cells returns the column of key in every row, derived from one 64-bit
+// hash by double hashing.
+func (c *countMin) cells(key string) []int {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	h1, h2 := uint32(sum), uint32(sum>>32)|1
+	cols := make([]int, len(c.counters))
+	for i := range cols {
+		cols[i] = int((h1 + uint32(i)*h2) % uint32(c.width))
+	}
+	return cols
+}
+
+func (c *countMin) add(key string, w float64) {
+	c.total += w
+	for row, col := range c.cells(key) {
+		c.counters[row][col] += w
+	}
+}
+
+func (c *countMin) estimate(key string) float64 {
+	est := -1.0
+	for row, col := range c.cells(key) {
+		if v := c.counters[row][col]; est < 0 || v < est {
+			est = v
+		}
+	}
+	return est
+}
+
+// errorBound is the overcount that estimates stay within with high
+// probability. An estimate below it cannot be told apart from collisions.
+func (c *countMin) errorBound() float64 {
+	return math.E / float64(c.width) * c.total
+}
+
+func (c *countMin) scale(f float64) {
+	c.total *= f
+	for _, row := range c.counters {
+		for i := range row {
+			row[i] *= f
+		}
+	}
+}
+
+// spaceSaving keeps the k keys with the largest counts (Metwally et al.).
+// When full, a new key replaces the smallest entry and inherits its count,
+// so a tracked count overestimates by at most its err.
+type spaceSaving struct {
+	k       int
+	entries map[string]*ssEntry
+	heap    ssHeap // min-heap on count
+}
+
+type ssEntry struct {
+	key   string
+	count float64
+	err   float64
+	index int
+}
+
+func newSpaceSaving(k int) *spaceSaving {
+	return &spaceSaving{k: k, entries: make(map[string]*ssEntry, k)}
+}
+
+func (s *spaceSaving) add(key string, w float64) {
+	if e, ok := s.entries[key]; ok {
+		e.count += w
+		heap.Fix(&s.heap, e.index)
+		return
+	}
+	if len(s.entries) < s.k {
+		e := &ssEntry{key: key, count: w}
+		s.entries[key] = e
+		heap.Push(&s.heap, e)
+		return
+	}
+	min := s.heap[0]
+	delete(s.entries, min.key)
+	min.key, min.err = key, min.count
+	min.count += w
+	s.entries[key] = min
+	heap.Fix(&s.heap, 0)
+}
+
+// scale multiplies every count; the heap order is unchanged.
+func (s *spaceSaving) scale(f float64) {
+	for _, e := range s.entries {
+		e.count *= f
+		e.err *= f
+	}
+}
+
+type ssHeap []*ssEntry
+
+func (h ssHeap) Len() int           { return len(h) }
+func (h ssHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+func (h ssHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *ssHeap) Push(x any) {
+	e := x.(*ssEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *ssHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,86 @@
+// internal/replication/sketch_test.go
+package replication
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// zipf returns n accesses over keys "k0".."k<keys-1>" with a skewed
+// popularity, and the exact count of each key.
+func zipf(n, keys int) ([]string, map[string]float64) {
+	rng := rand.New(rand.NewSource(1))
+	z := rand.NewZipf(rng, 1.2, 1, uint64(keys-1))
+	stream := make([]string, n)
+	exact := make(map[string]float64)
+	for i := range stream {
+		stream[i] = fmt.Sprintf("k%d", z.Uint64())
+		exact[stream[i]]++
+	}
+	return stream, exact
+}
+
+func TestCountMin(t *testing.T) {
+	stream, exact := zipf(100000, 5000)
+	c := newCountMin(272, 5) // e/272 = 1% of the total
+	for _, key := range stream {
+		c.add(key, 1)
+	}
+	if c.total != float64(len(stream)) {
+		t.Fatalf("total = %v, want %d", c.total, len(stream))
+	}
+	bound := c.errorBound()
+	over := 0
+	for key, n := range exact {
+		est := c.estimate(key)
+		if est < n {
+			t.Fatalf("estimate(%s) = %v undercounts %v", key, est, n)
+		}
+		if est-n > bound {
+			over++
+		}
+	}
+	// the bound holds with probability 1-e^-5 per key
+	if over > len(exact)/100 {
+		t.Fatalf("%d of %d estimates exceed the error bound %v", over, len(exact), bound)
+	}
+
+	c.scale(0.5)
+	if est, n := c.estimate("k0"), exact["k0"]; est < n/2 || c.total != float64(len(stream))/2 {
+		t.Fatalf("after scaling by 0.5: estimate %v for %v, total %v", est, n, c.total)
+	}
+}
+
+func TestSpaceSaving(t *testing.T) {
+	stream, exact := zipf(100000, 5000)
+	const k = 50
+	s := newSpaceSaving(k)
+	for _, key := range stream {
+		s.add(key, 1)
+	}
+	if len(s.entries) != k || s.heap.Len() != k {
+		t.Fatalf("tracking %d entries (heap %d), want %d", len(s.entries), s.heap.Len(), k)
+	}
+	minCount := math.Inf(1)
+	for key, e := range s.entries {
+		// counts overestimate by at most err
+		if e.count < exact[key] || e.count-e.err > exact[key] {
+			t.Fatalf("%s: count %v err %v, exact %v", key, e.count, e.err, exact[key])
+		}
+		minCount = math.Min(minCount, e.count)
+		if s.heap[e.index] != e {
+			t.Fatalf("%s: heap index %d is stale", key, e.index)
+		}
+	}
+	if s.heap[0].count != minCount {
+		t.Fatalf("heap root count %v, want the minimum %v", s.heap[0].count, minCount)
+	}
+	// any key more frequent than total/k is guaranteed to be tracked
+	for key, n := range exact {
+		if _, ok := s.entries[key]; n > float64(len(stream))/k && !ok {
+			t.Fatalf("heavy hitter %s (%v accesses) not tracked", key, n)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package replication
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// DefaultHalfLife is how long it takes an idle key's access rate to halve.
+// An hour keeps up with daily shifts in geo traffic while smoothing bursts.
+const DefaultHalfLife = time.Hour
+
+// Default sizes of the per-region heavy-hitter trackers: about 512KB of
+// counters each.
+const (
+	DefaultTopK        = 1000
+	DefaultSketchWidth = 1 << 14
+	DefaultSketchDepth = 4
+)
+
+// rescaleAfter is the age, in decay time constants, at which a tracker
+// folds the decay into its counters to keep them from overflowing.
+const rescaleAfter = 32
+
+// tau converts a half-life into the decay time constant in seconds.
+func tau(halfLife time.Duration) float64 {
+	return halfLife.Seconds() / math.Ln2
+}
+
+// accessTracker estimates the exponentially decayed access rate of keys
+// from one region in bounded memory. A count-min sketch estimates any key's
+// count and a space-saving summary keeps the heaviest hitters, which are
+// the keys the manager evaluates.
+//
+// Decay uses forward decay: an access at time t adds exp((t-landmark)/tau),
+// and counts are scaled by exp(-(now-landmark)/tau) when read, so decaying
+// never has to touch every counter.
+type accessTracker struct {
+	halfLife time.Duration
+	landmark time.Time
+	sketch   *countMin
+	top      *spaceSaving
+}
+
+func newAccessTracker(halfLife time.Duration, width, depth, k int, now time.Time) *accessTracker {
+	return &accessTracker{
+		halfLife: halfLife,
+		landmark: now,
+		sketch:   newCountMin(width, depth),
+		top:      newSpaceSaving(k),
+	}
+}
+
+// add records n accesses to key at now.
+func (t *accessTracker) add(key string, n float64, now time.Time) {
+	age := now.Sub(t.landmark).Seconds() / tau(t.halfLife)
+	if age > rescaleAfter {
+		scale := math.Exp(-age)
+		t.sketch.scale(scale)
+		t.top.scale(scale)
+		t.landmark = now
+		age = 0
+	}
+	w := n * math.Exp(age)
+	t.sketch.add(key, w)
+	t.top.add(key, w)
+}
+
+// rate returns the estimated decayed accesses per second of key at now.
+// Only heavy hitters get a rate: the tighter of their sketch and summary
+// counts, and only once it exceeds the sketch's error bound. Any other
+// estimate may be nothing but collisions with heavier keys, so it is 0.
+func (t *accessTracker) rate(key string, now time.Time) float64 {
+	e, ok := t.top.entries[key]
+	if !ok {
+		return 0
+	}
+	count := math.Min(t.sketch.estimate(key), e.count)
+	if count <= t.sketch.errorBound() {
+		return 0
+	}
+	return t.toRate(count, now)
+}
+
+// hot returns the tracked heavy hitters whose rate is at least minRate.
+func (t *accessTracker) hot(now time.Time, minRate float64) []string {
+	var keys []string
+	for key := range t.top.entries {
+		if rate := t.rate(key, now); rate > 0 && rate >= minRate {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (t *accessTracker) toRate(count float64, now time.Time) float64 {
+	age := now.Sub(t.landmark).Seconds() / tau(t.halfLife)
+	return count * math.Exp(-age) / tau(t.halfLife)
+}
@@ -0,0 +1,46 @@
+// internal/replication/stats_test.go
+package replication
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/metadata"
+)
+
+func TestAccessTrackerNoPhantomRates(t *testing.T) {
+	now := time.Now()
+	tr := newAccessTracker(DefaultHalfLife, 64, 4, 8, now)
+	// many cold keys fill every sketch cell well above a once-a-day rate
+	for i := 0; i < 5000; i++ {
+		tr.add(fmt.Sprintf("cold%d", i), 1, now)
+	}
+	for i := 0; i < 2000; i++ {
+		tr.add("hot", 1, now)
+	}
+
+	if rate := tr.rate("hot", now); rate == 0 {
+		t.Fatal("heavy hitter has no rate")
+	}
+	if est := tr.sketch.estimate("never-seen"); est == 0 {
+		t.Fatal("test sketch too wide to show collisions")
+	}
+	if rate := tr.rate("never-seen", now); rate != 0 {
+		t.Fatalf("unseen key rate = %v, want 0", rate)
+	}
+	minRate := 1.0 / (24 * 60 * 60)
+	hot := tr.hot(now, minRate)
+	if len(hot) != 1 || hot[0] != "hot" {
+		t.Fatalf("hot = %v, want [hot]", hot)
+	}
+}
+
+func TestManagerZeroSketchSizes(t *testing.T) {
+	m := NewManager(testRing(t, 3, nil), metadata.NewMemory(), 1, DecisionFunc(func(string, string) (bool, float64) { return false, 0 }))
+	m.TopK, m.SketchWidth, m.SketchDepth, m.HalfLife = 0, 0, -1, 0
+	m.RecordReads("k", "eu", 10)
+	if rates := m.Rates("k"); rates.Reads["eu"] == 0 {
+		t.Fatalf("Rates = %+v with default-sized sketches, want reads from eu", rates)
+	}
+}
@@ -0,0 +1,193 @@
+package replication
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/internal/ratelimit"
+)
+
+// changeQueue is a max-heap of changes by priority.
+type changeQueue []Change
+
+func (q changeQueue) Len() int            { return len(q) }
+func (q changeQueue) Less(i, j int) bool  { return q[i].Priority > q[j].Priority }
+func (q changeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *changeQueue) Push(x interface{}) { *q = append(*q, x.(Change)) }
+func (q *changeQueue) Pop() interface{} {
+	old := *q
+	ch := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return ch
+}
+
+// moveSlots counts running moves, overall and per node, so that a burst
+// of changes cannot saturate the cluster or a single node.
+type moveSlots struct {
+	mu     sync.Mutex
+	total  int
+	active map[string]int
+	wake   chan struct{} // closed and replaced on every release
+}
+
+func newMoveSlots() *moveSlots {
+	return &moveSlots{active: make(map[string]int), wake: make(chan struct{})}
+}
+
+// acquire blocks until a move touching nodes fits within maxTotal moves
+// overall and maxPerNode per node. Non-positive limits are unlimited.
+func (s *moveSlots) acquire(ctx context.Context, nodes []string, maxTotal, maxPerNode int) error {
+	for {
+		s.mu.Lock()
+		if s.fits(nodes, maxTotal, maxPerNode) {
+			s.total++
+			for _, n := range nodes {
+				s.active[n]++
+			}
+			s.mu.Unlock()
+			return nil
+		}
+		wake := s.wake
+		s.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+		}
+	}
+}
+
+// fits reports whether another move on nodes stays in bounds. Caller holds s.mu.
+func (s *moveSlots) fits(nodes []string, maxTotal, maxPerNode int) bool {
+	if maxTotal > 0 && s.total >= maxTotal {
+		return false
+	}
+	if maxPerNode > 0 {
+		for _, n := range nodes {
+			if s.active[n] >= maxPerNode {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (s *moveSlots) release(nodes []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.total--
+	for _, n := range nodes {
+		if s.active[n]--; s.active[n] <= 0 {
+			delete(s.active, n)
+		}
+	}
+	close(s.wake)
+	s.wake = make(chan struct{})
+}
+
+// bandwidth paces the bytes copied by moves, overall and per node.
+type bandwidth struct {
+	mu    sync.Mutex
+	total *ratelimit.Limiter
+	nodes map[string]*ratelimit.Limiter
+}
+
+// charge accounts n copied bytes against the overall limit and that of
+// every node involved, blocking while any of them is over its rate. Copies
+// are charged once done, so it is the following moves that wait.
+func (b *bandwidth) charge(ctx context.Context, nodes []string, n int, totalRate, nodeRate float64) error {
+	b.mu.Lock()
+	if b.total == nil && totalRate > 0 {
+		b.total = ratelimit.New(totalRate)
+	}
+	limiters := []*ratelimit.Limiter{b.total}
+	if nodeRate > 0 {
+		if b.nodes == nil {
+			b.nodes = make(map[string]*ratelimit.Limiter)
+		}
+		for _, node := range nodes {
+			l, ok := b.nodes[node]
+			if !ok {
+				l = ratelimit.New(nodeRate)
+				b.nodes[node] = l
+			}
+			limiters = append(limiters, l)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, l := range limiters {
+		if err := l.Wait(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyAll applies changes in descending priority, at most budget of them
+// (non-positive is unlimited), within the manager's move limits. It waits
+// for every started move and returns the applied keys, the failed ones,
+// and the changes left over for lack of budget. If ctx ends while waiting
+// for a move slot, every change not yet started fails with its error.
+func (m *Manager) applyAll(ctx context.Context, changes []Change, budget int) (applied []string, failed map[string]error, deferred []Change) {
+	q := append(changeQueue(nil), changes...)
+	heap.Init(&q)
+	failed = make(map[string]error)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var acquireErr error
+	for started := 0; q.Len() > 0; started++ {
+		if budget > 0 && started >= budget {
+			break
+		}
+		ch := heap.Pop(&q).(Change)
+		nodes := moveNodes(ch)
+		if err := m.slots.acquire(ctx, nodes, m.MaxConcurrentMoves, m.MaxMovesPerNode); err != nil {
+			// recorded once the started moves are done with failed
+			acquireErr = err
+			heap.Push(&q, ch)
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer m.slots.release(nodes)
+			err := m.apply(ctx, ch)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed[ch.Key] = err
+				return
+			}
+			applied = append(applied, ch.Key)
+		}()
+	}
+	wg.Wait()
+	for q.Len() > 0 {
+		ch := heap.Pop(&q).(Change)
+		if acquireErr != nil {
+			// ctx is done: nothing else can start
+			failed[ch.Key] = acquireErr
+			continue
+		}
+		deferred = append(deferred, ch)
+	}
+	return applied, failed, deferred
+}
+
+// moveNodes returns the nodes a change's copy loads: the current primary,
+// which serves as the source, and every added replica.
+func moveNodes(ch Change) []string {
+	var nodes []string
+	if len(ch.Current) > 0 {
+		nodes = append(nodes, ch.Current[0])
+	}
+	for _, n := range minus(ch.Proposed, ch.Current) {
+		if !contains(nodes, n) {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,198 @@
+// internal/telemetry/telemetry.go
+package telemetry
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	pb "google.golang.org/protobuf/proto"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/proto"
+)
+
+// Recorder receives access counts; replication.Manager implements it.
+type Recorder interface {
+	RecordReads(key, region string, n int)
+	RecordWrites(key, region string, n int)
+}
+
+// Server implements proto.TelemetryServer on the placement manager by
+// forwarding every reported count to a Recorder.
+type Server struct {
+	proto.UnimplementedTelemetryServer
+	rec Recorder
+}
+
+// NewServer returns a telemetry server feeding rec.
+func NewServer(rec Recorder) *Server {
+	return &Server{rec: rec}
+}
+
+// Report records a proxy's batch of access counts.
+func (s *Server) Report(ctx context.Context, req *proto.AccessReport) (*proto.AccessReportReply, error) {
+	for _, c := range req.Counts {
+		if c.Reads > 0 {
+			s.rec.RecordReads(c.Key, c.Region, int(c.Reads))
+		}
+		if c.Writes > 0 {
+			s.rec.RecordWrites(c.Key, c.Region, int(c.Writes))
+		}
+	}
+	return &proto.AccessReportReply{}, nil
+}
+
+// ManagerElection is the metadata election placement managers campaign in;
+// its leader's value is the address proxies report to.
+const ManagerElection = "placement-manager"
+
+// maxPending bounds the distinct key/region pairs a Reporter buffers, so a
+// manager outage cannot grow a proxy's memory without limit.
+const maxPending = 100000
+
+// maxBatchBytes bounds the encoded size of one Report, well below gRPC's
+// default 4 MiB message limit; larger flushes are split across RPCs.
+const maxBatchBytes = 1 << 20
+
+type counterKey struct {
+	key, region string
+}
+
+// Reporter aggregates access counts in a proxy and pushes them to a remote
+// manager's Telemetry service at a fixed interval. Counts that cannot be
+// delivered are dropped: telemetry is best effort.
+type Reporter struct {
+	proxyID string
+	addr    string
+
+	// Resolve, if set, is asked for the manager's address before every
+	// flush, so reports follow the elected leader instead of going to addr.
+	// While it fails, counts stay buffered.
+	Resolve func(ctx context.Context) (string, error)
+
+	mu      sync.Mutex
+	pending map[counterKey]*proto.AccessCount
+	dropped int
+}
+
+// NewReporter returns a reporter that sends to the manager at addr,
+// identifying itself as proxyID.
+func NewReporter(proxyID, addr string) *Reporter {
+	return &Reporter{
+		proxyID: proxyID,
+		addr:    addr,
+		pending: make(map[counterKey]*proto.AccessCount),
+	}
+}
+
+// RecordRead counts one read of key from region.
+func (r *Reporter) RecordRead(key, region string) {
+	r.count(key, region, 1, 0)
+}
+
+// RecordWrite counts one write of key from region.
+func (r *Reporter) RecordWrite(key, region string) {
+	r.count(key, region, 0, 1)
+}
+
+func (r *Reporter) count(key, region string, reads, writes uint32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k := counterKey{key, region}
+	c, ok := r.pending[k]
+	if !ok {
+		if len(r.pending) >= maxPending {
+			r.dropped++
+			return
+		}
+		c = &proto.AccessCount{Key: key, Region: region}
+		r.pending[k] = c
+	}
+	c.Reads += reads
+	c.Writes += writes
+}
+
+// Run flushes the aggregated counts every interval until ctx is done.
+func (r *Reporter) Run(ctx context.Context, interval time.Duration) {
+	var conn *grpc.ClientConn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		addr := r.addr
+		if r.Resolve != nil {
+			var err error
+			if addr, err = r.Resolve(ctx); err != nil {
+				log.Printf("telemetry: find manager: %v", err)
+				continue
+			}
+		}
+		if conn == nil || conn.Target() != addr {
+			if conn != nil {
+				conn.Close()
+			}
+			var err error
+			if conn, err = grpc.DialContext(ctx, addr, grpc.WithInsecure()); err != nil {
+				log.Printf("telemetry: dial %s: %v", addr, err)
+				conn = nil
+				continue
+			}
+		}
+		r.flush(ctx, proto.NewTelemetryClient(conn), addr, interval)
+	}
+}
+
+func (r *Reporter) flush(ctx context.Context, client proto.TelemetryClient, addr string, timeout time.Duration) {
+	r.mu.Lock()
+	counts := make([]*proto.AccessCount, 0, len(r.pending))
+	for _, c := range r.pending {
+		counts = append(counts, c)
+	}
+	r.pending = make(map[counterKey]*proto.AccessCount)
+	dropped := r.dropped
+	r.dropped = 0
+	r.mu.Unlock()
+
+	if dropped > 0 {
+		log.Printf("telemetry: dropped %d counts over the buffer limit", dropped)
+	}
+	for _, batch := range r.batches(counts) {
+		sendCtx, cancel := context.WithTimeout(ctx, timeout)
+		_, err := client.Report(sendCtx, batch)
+		cancel()
+		if err != nil {
+			log.Printf("telemetry: report %d counts to %s: %v", len(batch.Counts), addr, err)
+		}
+	}
+}
+
+// batches splits counts into reports of at most maxBatchBytes each.
+func (r *Reporter) batches(counts []*proto.AccessCount) []*proto.AccessReport {
+	var out []*proto.AccessReport
+	var batch *proto.AccessReport
+	size := 0
+	for _, c := range counts {
+		// the count plus its field tag and length prefix
+		n := pb.Size(c) + 6
+		if batch == nil || size+n > maxBatchBytes {
+			batch = &proto.AccessReport{Proxy: r.proxyID}
+			size = len(r.proxyID) + 6
+			out = append(out, batch)
+		}
+		batch.Counts = append(batch.Counts, c)
+		size += n
+	}
+	return out
+}
@@ -0,0 +1,33 @@
+// internal/telemetry/telemetry_test.go
+package telemetry
+
+import (
+	"strings"
+	"testing"
+
+	pb "google.golang.org/protobuf/proto"
+
+	"github.com/ksharma120497/adaptive-geo-distributed-database/proto"
+)
+
+func TestBatchesFitMessageLimit(t *testing.T) {
+	r := NewReporter("proxy-1", "manager:1")
+	counts := make([]*proto.AccessCount, maxPending)
+	for i := range counts {
+		counts[i] = &proto.AccessCount{Key: strings.Repeat("k", 100), Region: "us-east", Reads: 1 << 30, Writes: 1 << 30}
+	}
+	batches := r.batches(counts)
+	if len(batches) < 2 {
+		t.Fatalf("%d batches, want the counts split", len(batches))
+	}
+	total := 0
+	for _, b := range batches {
+		if n := pb.Size(b); n > maxBatchBytes {
+			t.Fatalf("batch of %d bytes over the %d limit", n, maxBatchBytes)
+		}
+		total += len(b.Counts)
+	}
+	if total != len(counts) {
+		t.Fatalf("batches hold %d counts, want %d", total, len(counts))
+	}
+}
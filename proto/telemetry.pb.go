@@ -0,0 +1,250 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v6.31.1
+// source: proto/telemetry.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// AccessCount aggregates the operations a proxy saw for one key from one
+// client region since its last report.
+type AccessCount struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Region        string                 `protobuf:"bytes,2,opt,name=region,proto3" json:"region,omitempty"`
+	Reads         uint32                 `protobuf:"varint,3,opt,name=reads,proto3" json:"reads,omitempty"`
+	Writes        uint32                 `protobuf:"varint,4,opt,name=writes,proto3" json:"writes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AccessCount) Reset() {
+	*x = AccessCount{}
+	mi := &file_proto_telemetry_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AccessCount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AccessCount) ProtoMessage() {}
+
+func (x *AccessCount) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_telemetry_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AccessCount.ProtoReflect.Descriptor instead.
+func (*AccessCount) Descriptor() ([]byte, []int) {
+	return file_proto_telemetry_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *AccessCount) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *AccessCount) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *AccessCount) GetReads() uint32 {
+	if x != nil {
+		return x.Reads
+	}
+	return 0
+}
+
+func (x *AccessCount) GetWrites() uint32 {
+	if x != nil {
+		return x.Writes
+	}
+	return 0
+}
+
+type AccessReport struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Proxy         string                 `protobuf:"bytes,1,opt,name=proxy,proto3" json:"proxy,omitempty"`
+	Counts        []*AccessCount         `protobuf:"bytes,2,rep,name=counts,proto3" json:"counts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AccessReport) Reset() {
+	*x = AccessReport{}
+	mi := &file_proto_telemetry_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AccessReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AccessReport) ProtoMessage() {}
+
+func (x *AccessReport) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_telemetry_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AccessReport.ProtoReflect.Descriptor instead.
+func (*AccessReport) Descriptor() ([]byte, []int) {
+	return file_proto_telemetry_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *AccessReport) GetProxy() string {
+	if x != nil {
+		return x.Proxy
+	}
+	return ""
+}
+
+func (x *AccessReport) GetCounts() []*AccessCount {
+	if x != nil {
+		return x.Counts
+	}
+	return nil
+}
+
+type AccessReportReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AccessReportReply) Reset() {
+	*x = AccessReportReply{}
+	mi := &file_proto_telemetry_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AccessReportReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AccessReportReply) ProtoMessage() {}
+
+func (x *AccessReportReply) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_telemetry_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AccessReportReply.ProtoReflect.Descriptor instead.
+func (*AccessReportReply) Descriptor() ([]byte, []int) {
+	return file_proto_telemetry_proto_rawDescGZIP(), []int{2}
+}
+
+var File_proto_telemetry_proto protoreflect.FileDescriptor
+
+const file_proto_telemetry_proto_rawDesc = "" +
+	"\n" +
+	"\x15proto/telemetry.proto\x12\x05proto\"e\n" +
+	"\vAccessCount\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x16\n" +
+	"\x06region\x18\x02 \x01(\tR\x06region\x12\x14\n" +
+	"\x05reads\x18\x03 \x01(\rR\x05reads\x12\x16\n" +
+	"\x06writes\x18\x04 \x01(\rR\x06writes\"P\n" +
+	"\fAccessReport\x12\x14\n" +
+	"\x05proxy\x18\x01 \x01(\tR\x05proxy\x12*\n" +
+	"\x06counts\x18\x02 \x03(\v2\x12.proto.AccessCountR\x06counts\"\x13\n" +
+	"\x11AccessReportReply2D\n" +
+	"\tTelemetry\x127\n" +
+	"\x06Report\x12\x13.proto.AccessReport\x1a\x18.proto.AccessReportReplyB/Z-adaptive-geo-distributed-database/proto;protob\x06proto3"
+
+var (
+	file_proto_telemetry_proto_rawDescOnce sync.Once
+	file_proto_telemetry_proto_rawDescData []byte
+)
+
+func file_proto_telemetry_proto_rawDescGZIP() []byte {
+	file_proto_telemetry_proto_rawDescOnce.Do(func() {
+		file_proto_telemetry_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_telemetry_proto_rawDesc), len(file_proto_telemetry_proto_rawDesc)))
+	})
+	return file_proto_telemetry_proto_rawDescData
+}
+
+var file_proto_telemetry_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_proto_telemetry_proto_goTypes = []any{
+	(*AccessCount)(nil),       // 0: proto.AccessCount
+	(*AccessReport)(nil),      // 1: proto.AccessReport
+	(*AccessReportReply)(nil), // 2: proto.AccessReportReply
+}
+var file_proto_telemetry_proto_depIdxs = []int32{
+	0, // 0: proto.AccessReport.counts:type_name -> proto.AccessCount
+	1, // 1: proto.Telemetry.Report:input_type -> proto.AccessReport
+	2, // 2: proto.Telemetry.Report:output_type -> proto.AccessReportReply
+	2, // [2:3] is the sub-list for method output_type
+	1, // [1:2] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_proto_telemetry_proto_init() }
+func file_proto_telemetry_proto_init() {
+	if File_proto_telemetry_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_telemetry_proto_rawDesc), len(file_proto_telemetry_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_telemetry_proto_goTypes,
+		DependencyIndexes: file_proto_telemetry_proto_depIdxs,
+		MessageInfos:      file_proto_telemetry_proto_msgTypes,
+	}.Build()
+	File_proto_telemetry_proto = out.File
+	file_proto_telemetry_proto_goTypes = nil
+	file_proto_telemetry_proto_depIdxs = nil
+}